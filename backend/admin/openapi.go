@@ -0,0 +1,17 @@
+package admin
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed openapi.json
+var openapi []byte
+
+// OpenAPIHandler 管理接口的 OpenAPI 描述，无需令牌即可访问
+func OpenAPIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(openapi)
+	})
+}
@@ -0,0 +1,31 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// errorBody 错误响应
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// WriteJSON 输出 JSON 响应
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if v != nil {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+// WriteError 输出错误响应
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteJSON(w, status, errorBody{Error: message})
+}
+
+// ReadJSON 解析 JSON 请求体
+func ReadJSON(r *http.Request, v any) error {
+	defer r.Body.Close()
+	return json.NewDecoder(http.MaxBytesReader(nil, r.Body, 4<<20)).Decode(v)
+}
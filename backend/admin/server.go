@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/config"
+)
+
+// Server 本地管理接口服务器，提供监控指标等管理功能
+type Server struct {
+	config  *config.AdminConfig
+	mux     *http.ServeMux
+	server  *http.Server
+	running bool
+	mu      sync.Mutex
+}
+
+// NewServer 创建管理接口服务器
+func NewServer(config *config.AdminConfig) *Server {
+	return &Server{
+		config: config,
+		mux:    http.NewServeMux(),
+	}
+}
+
+// Handle 注册管理接口路由，需在 Start 之前调用
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start 启动管理接口服务器
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("管理接口已在运行")
+	}
+
+	listener, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf("管理接口监听失败: %w", err)
+	}
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok && !addr.IP.IsLoopback() {
+		slog.Warn("管理接口未监听本地回环地址，可能被其他主机访问", "listen", s.config.Listen)
+	}
+
+	s.server = &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.running = true
+	slog.Info("管理接口启动成功", "listen", listener.Addr().String())
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("管理接口运行出错", "error", err)
+			s.mu.Lock()
+			s.running = false
+			s.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// Stop 停止管理接口服务器
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running || s.server == nil {
+		return nil
+	}
+	s.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("管理接口停止失败: %w", err)
+	}
+	slog.Info("管理接口已停止")
+	return nil
+}
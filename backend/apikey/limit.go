@@ -0,0 +1,51 @@
+package apikey
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"golang.org/x/time/rate"
+)
+
+var (
+	// ErrForbidden 密钥无权访问请求的渠道组或模型
+	ErrForbidden = errors.New("客户端密钥无权访问")
+	// ErrRateLimited 密钥请求过于频繁
+	ErrRateLimited = errors.New("客户端密钥请求过于频繁")
+	// ErrBudgetExceeded 密钥的 token 预算已用完
+	ErrBudgetExceeded = errors.New("客户端密钥 token 预算已用完")
+)
+
+// Authorize 在选择渠道前校验密钥的访问限制，used 为密钥已使用的 token 数
+func (m *Manager) Authorize(key *Key, group, model string, used uint64) error {
+	if len(key.AllowedGroups) != 0 && !slices.Contains(key.AllowedGroups, group) {
+		return fmt.Errorf("%w渠道组: %s", ErrForbidden, group)
+	}
+	if len(key.AllowedModels) != 0 && !slices.ContainsFunc(key.AllowedModels, func(pattern string) bool {
+		return channel.MatchModel(pattern, model)
+	}) {
+		return fmt.Errorf("%w模型: %s", ErrForbidden, model)
+	}
+	if key.TokenBudget != 0 && used >= key.TokenBudget {
+		return fmt.Errorf("%w（%d/%d）", ErrBudgetExceeded, used, key.TokenBudget)
+	}
+	if key.RateLimit > 0 && !m.limiter(key).Allow() {
+		return fmt.Errorf("%w，每分钟最多 %d 次", ErrRateLimited, key.RateLimit)
+	}
+	return nil
+}
+
+// limiter 获取密钥的限流器，令牌按分钟均匀补充，允许突发 RateLimit 次请求
+func (m *Manager) limiter(key *Key) *rate.Limiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limiter, ok := m.limiters[key.ID]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(float64(key.RateLimit)/60), key.RateLimit)
+		m.limiters[key.ID] = limiter
+	}
+	return limiter
+}
@@ -0,0 +1,61 @@
+package apikey
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		group   string
+		model   string
+		used    uint64
+		wantErr error
+	}{
+		{name: "unlimited", group: "api.openai.com", model: "gpt-4o", used: 1 << 40},
+		{name: "allowed group", limits: Limits{AllowedGroups: []string{"api.openai.com"}}, group: "api.openai.com", model: "gpt-4o"},
+		{name: "forbidden group", limits: Limits{AllowedGroups: []string{"api.openai.com"}}, group: "api.anthropic.com", model: "claude", wantErr: ErrForbidden},
+		{name: "wildcard model", limits: Limits{AllowedModels: []string{"claude-*"}}, group: "api.anthropic.com", model: "claude-sonnet-4"},
+		{name: "forbidden model", limits: Limits{AllowedModels: []string{"claude-*"}}, group: "api.anthropic.com", model: "gpt-4o", wantErr: ErrForbidden},
+		{name: "under budget", limits: Limits{TokenBudget: 1000}, model: "gpt-4o", used: 999},
+		{name: "budget reached", limits: Limits{TokenBudget: 1000}, model: "gpt-4o", used: 1000, wantErr: ErrBudgetExceeded},
+		{name: "group checked before budget", limits: Limits{AllowedGroups: []string{"a"}, TokenBudget: 1}, group: "b", used: 5, wantErr: ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(t.TempDir())
+			key := &Key{ID: "id", Limits: tt.limits}
+			if err := m.Authorize(key, tt.group, tt.model, tt.used); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authorize() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizeRateLimit(t *testing.T) {
+	m := NewManager(t.TempDir())
+	key := &Key{ID: "id", Limits: Limits{RateLimit: 3}}
+	for i := range 3 {
+		if err := m.Authorize(key, "", "", 0); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if err := m.Authorize(key, "", "", 0); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("burst exceeded: %v, want %v", err, ErrRateLimited)
+	}
+	// 其他密钥使用独立的限流器
+	if err := m.Authorize(&Key{ID: "other", Limits: Limits{RateLimit: 3}}, "", "", 0); err != nil {
+		t.Fatalf("other key limited: %v", err)
+	}
+
+	// 修改限制后重新创建限流器
+	m.keys[key.ID] = key
+	if err := m.SetLimits(key.ID, Limits{RateLimit: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Authorize(key, "", "", 0); err != nil {
+		t.Fatalf("after SetLimits: %v", err)
+	}
+}
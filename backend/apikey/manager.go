@@ -0,0 +1,236 @@
+package apikey
+
+import (
+	"cmp"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// keyPrefix 本地签发密钥的前缀，便于与上游密钥区分
+const keyPrefix = "sk-chm-"
+
+// Key 本地签发的客户端密钥，只保存密钥的哈希值
+type Key struct {
+	ID      string `json:"id"`      // 密钥ID
+	Name    string `json:"name"`    // 名称（使用者）
+	Hint    string `json:"hint"`    // 密钥提示，仅保留首尾少量字符
+	Hash    string `json:"hash"`    // 密钥的 SHA-256 哈希
+	Created int64  `json:"created"` // 创建时间（毫秒时间戳）
+	Revoked bool   `json:"revoked"` // 是否已吊销
+	Limits
+}
+
+// Limits 客户端密钥的访问限制，零值表示不限制
+type Limits struct {
+	AllowedGroups []string `json:"allowed_groups,omitempty"` // 允许访问的渠道组
+	AllowedModels []string `json:"allowed_models,omitempty"` // 允许请求的模型，支持通配符
+	RateLimit     int      `json:"rate_limit,omitempty"`     // 每分钟最大请求数
+	TokenBudget   uint64   `json:"token_budget,omitempty"`   // token 总预算（输入+输出）
+}
+
+// Manager 客户端密钥管理器
+type Manager struct {
+	path     string
+	keys     map[string]*Key          // key: Key.ID
+	limiters map[string]*rate.Limiter // key: Key.ID，按需创建
+	mu       sync.RWMutex
+}
+
+// NewManager 创建客户端密钥管理器
+func NewManager(dataDir string) *Manager {
+	m := &Manager{
+		path:     filepath.Join(dataDir, "keys.json"),
+		keys:     make(map[string]*Key),
+		limiters: make(map[string]*rate.Limiter),
+	}
+	if err := m.Load(); err != nil {
+		slog.Error("加载客户端密钥失败", "path", m.path, "error", err)
+	}
+	return m
+}
+
+// Load 从文件加载客户端密钥
+func (m *Manager) Load() error {
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*Key)
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("解析客户端密钥文件失败: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = keys
+	return nil
+}
+
+// save 保存客户端密钥，调用方需持有锁
+func (m *Manager) save() error {
+	data, err := json.MarshalIndent(m.keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, data, 0600)
+}
+
+// GenerateResult 生成密钥的结果，Secret 只在生成时返回一次
+type GenerateResult struct {
+	Key    *Key   `json:"key"`
+	Secret string `json:"secret"`
+}
+
+// Generate 生成新的客户端密钥
+func (m *Manager) Generate(name string, limits Limits) (*GenerateResult, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("密钥名称不能为空")
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	secret := keyPrefix + hex.EncodeToString(b)
+
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	key := &Key{
+		ID:      hex.EncodeToString(id),
+		Name:    name,
+		Hint:    secret[:len(keyPrefix)+4] + "..." + secret[len(secret)-4:],
+		Hash:    hash(secret),
+		Created: time.Now().UnixMilli(),
+		Limits:  limits,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[key.ID] = key
+	if err := m.save(); err != nil {
+		delete(m.keys, key.ID)
+		return nil, fmt.Errorf("保存客户端密钥失败: %w", err)
+	}
+	slog.Info("生成客户端密钥", "id", key.ID, "name", name)
+	return &GenerateResult{Key: key, Secret: secret}, nil
+}
+
+// SetLimits 修改客户端密钥的访问限制
+func (m *Manager) SetLimits(id string, limits Limits) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.keys[id]
+	if !ok {
+		return fmt.Errorf("客户端密钥不存在: %s", id)
+	}
+	old := key.Limits
+	key.Limits = limits
+	if err := m.save(); err != nil {
+		key.Limits = old
+		return fmt.Errorf("保存客户端密钥失败: %w", err)
+	}
+	// 限流参数变化后重新创建限流器
+	delete(m.limiters, id)
+	slog.Info("修改客户端密钥限制", "id", id, "name", key.Name)
+	return nil
+}
+
+// Revoke 吊销客户端密钥
+func (m *Manager) Revoke(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.keys[id]
+	if !ok {
+		return fmt.Errorf("客户端密钥不存在: %s", id)
+	}
+	key.Revoked = true
+	if err := m.save(); err != nil {
+		key.Revoked = false
+		return fmt.Errorf("保存客户端密钥失败: %w", err)
+	}
+	slog.Info("吊销客户端密钥", "id", id, "name", key.Name)
+	return nil
+}
+
+// Delete 删除客户端密钥
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.keys[id]
+	if !ok {
+		return fmt.Errorf("客户端密钥不存在: %s", id)
+	}
+	delete(m.keys, id)
+	delete(m.limiters, id)
+	if err := m.save(); err != nil {
+		m.keys[id] = key
+		return fmt.Errorf("保存客户端密钥失败: %w", err)
+	}
+	slog.Info("删除客户端密钥", "id", id, "name", key.Name)
+	return nil
+}
+
+// List 列出所有客户端密钥，按创建时间排序
+func (m *Manager) List() []*Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := slices.Collect(maps.Values(m.keys))
+	slices.SortFunc(keys, func(a, b *Key) int {
+		return cmp.Compare(a.Created, b.Created)
+	})
+	return keys
+}
+
+// Validate 校验客户端密钥，返回对应密钥信息的副本
+func (m *Manager) Validate(secret string) (*Key, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("缺少客户端密钥")
+	}
+	digest := hash(secret)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, key := range m.keys {
+		if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(digest)) == 1 {
+			if key.Revoked {
+				return nil, fmt.Errorf("客户端密钥已吊销")
+			}
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("客户端密钥无效")
+}
+
+// hash 计算密钥的哈希值
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
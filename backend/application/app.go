@@ -3,20 +3,9 @@ package application
 import (
 	"context"
 	"log/slog"
-	"os"
-	"path/filepath"
-	"sync"
-	"time"
-
-	"github.com/sbgayhub/chameleon/backend/certificate"
-	"github.com/sbgayhub/chameleon/backend/channel"
-	"github.com/sbgayhub/chameleon/backend/config"
+
 	"github.com/sbgayhub/chameleon/backend/convert"
-	"github.com/sbgayhub/chameleon/backend/convert/anthropic"
-	"github.com/sbgayhub/chameleon/backend/convert/openai"
-	"github.com/sbgayhub/chameleon/backend/host"
-	"github.com/sbgayhub/chameleon/backend/server"
-	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/service"
 	"github.com/sbgayhub/chameleon/backend/tray"
 	"github.com/sbgayhub/chameleon/backend/updater"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -24,84 +13,31 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-// ProxyStatus 代理服务状态信息
-type ProxyStatus struct {
-	IsRunning         bool   `json:"isRunning"`
-	StartTime         int64  `json:"startTime"`
-	Uptime            int64  `json:"uptime"`
-	Port              uint16 `json:"port"`
-	ActiveConnections int    `json:"activeConnections"`
-	TotalRequests     int64  `json:"totalRequests"`
-	Mode              string `json:"mode"`
-}
-
-// App struct
+// App 图形界面应用，在代理服务的基础上增加托盘、窗口和更新等界面功能，
+// 代理服务的方法通过嵌入的 Service 一并绑定到前端
 type App struct {
-	running   bool
-	startTime time.Time
+	*service.Service
 
-	HostMgr    *host.Manager
-	ConfigMgr  *config.Manager
-	ChannelMgr *channel.Manager
-	TrayMgr    *tray.Manager
-	StatsMgr   *statistics.Manager
-	CertMgr    *certificate.CertManager
-	UpdateMgr  *updater.Manager
-
-	Server server.Server
+	TrayMgr   *tray.Manager
+	UpdateMgr *updater.Manager
 
 	ctx context.Context
-	mu  sync.RWMutex
-}
-
-// getDataDir 获取应用数据目录（exe同级的data文件夹）
-func getDataDir() string {
-	exePath, err := os.Executable()
-	if err != nil {
-		// 如果获取失败，回退到用户目录
-		homeDir, _ := os.UserHomeDir()
-		if homeDir == "" {
-			homeDir = os.TempDir()
-		}
-		return filepath.Join(homeDir, "github.com/sbgayhub/chameleon")
-	}
-
-	// 获取可执行文件所在目录
-	dir := filepath.Join(filepath.Dir(exePath), "data")
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		_ = os.MkdirAll(dir, 0755)
-	}
-	return dir
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	// 获取数据目录
-	dataDir := getDataDir()
-
-	// 初始化配置管理器
-	configMgr := config.NewManager(dataDir)
-	trayMgr := tray.NewManager()
-
-	// 初始化其他管理器
-	channelMgr := channel.NewManager(dataDir)
-	statsMgr := statistics.NewManager(dataDir)
-	certMgr := certificate.NewManager(dataDir)
-
-	// 加载代理配置
-	if err := channelMgr.LoadFromFile(); err != nil {
-		slog.Warn("加载代理配置失败", "error", err)
-	}
+	return New(service.DefaultDataDir())
+}
 
-	return &App{
-		CertMgr:    certMgr,
-		TrayMgr:    trayMgr,
-		ConfigMgr:  configMgr,
-		ChannelMgr: channelMgr,
-		StatsMgr:   statsMgr,
-		UpdateMgr:  updater.NewManager(),
-		running:    false,
+// New 使用指定的数据目录创建应用
+func New(dataDir string) *App {
+	app := &App{
+		Service:   service.New(dataDir),
+		TrayMgr:   tray.NewManager(),
+		UpdateMgr: updater.NewManager(),
 	}
+	app.OnProxyStatus = app.TrayMgr.UpdateProxyStatus
+	return app
 }
 
 // Startup is called when the app starts. The context is saved
@@ -128,12 +64,8 @@ func (app *App) Startup(ctx context.Context) {
 		app.TrayMgr.UpdateProxyStatus(false)
 	})
 
-	// 注册转换器
-	anthropic.RegistryOpenAIConverter()
-	anthropic.RegistryGeminiConverter()
-	anthropic.RegistryAnthropicConverter()
-	openai.RegistryOpenAIConverter()
-	openai.RegistryAnthropicConverter()
+	// 启动管理接口
+	app.Service.Start(ctx)
 
 	// 启动时检查更新
 	if app.ConfigMgr.GetConfig().General.CheckOnStartup {
@@ -143,6 +75,13 @@ func (app *App) Startup(ctx context.Context) {
 	slog.Info("app start")
 }
 
+// Shutdown is called when the app is about to quit, after the frontend
+// has been destroyed
+func (app *App) Shutdown(ctx context.Context) {
+	app.Service.Shutdown(ctx)
+	slog.Info("app shutdown")
+}
+
 // GetConverterNames 获取转换器名称列表
 func (app *App) GetConverterNames() []string {
 	return convert.GetRegistry().Names()
@@ -0,0 +1,69 @@
+package application
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sbgayhub/chameleon/backend/certificate"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ImportCA 选择自有 CA 文件导入，PEM 证书中不包含私钥时继续选择私钥文件，返回是否已导入（取消选择时为 false）
+func (app *App) ImportCA(passphrase string) (bool, error) {
+	certPath, err := runtime.OpenFileDialog(app.ctx, runtime.OpenDialogOptions{
+		Title: "选择 CA 证书（PEM 或 PKCS#12）",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "证书文件 (*.pem;*.crt;*.cer;*.p12;*.pfx)", Pattern: "*.pem;*.crt;*.cer;*.p12;*.pfx"},
+		},
+	})
+	if err != nil || certPath == "" {
+		return false, err
+	}
+
+	var keyPath string
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return false, fmt.Errorf("读取证书文件失败: %w", err)
+	}
+	if bytes.Contains(data, []byte("-----BEGIN")) && !bytes.Contains(data, []byte("PRIVATE KEY-----")) {
+		keyPath, err = runtime.OpenFileDialog(app.ctx, runtime.OpenDialogOptions{
+			Title:            "选择 CA 私钥（PEM）",
+			DefaultDirectory: filepath.Dir(certPath),
+			Filters: []runtime.FileFilter{
+				{DisplayName: "私钥文件 (*.pem;*.key)", Pattern: "*.pem;*.key"},
+			},
+		})
+		if err != nil || keyPath == "" {
+			return false, err
+		}
+	}
+	if err := app.CertMgr.ImportFile(certPath, keyPath, passphrase); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ExportCA 将当前 CA 证书按格式（pem|der）保存到选择的文件，返回保存路径（取消选择时为空）
+func (app *App) ExportCA(format string) (string, error) {
+	data, err := app.CertMgr.ExportCertAs(format)
+	if err != nil {
+		return "", err
+	}
+	name := "chameleon-ca.pem"
+	if format == certificate.FormatDER {
+		name = "chameleon-ca.cer"
+	}
+	path, err := runtime.SaveFileDialog(app.ctx, runtime.SaveDialogOptions{
+		Title:           "导出 CA 证书",
+		DefaultFilename: name,
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("写入证书失败: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,115 @@
+package application
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sbgayhub/chameleon/backend/convert"
+	"github.com/sbgayhub/chameleon/backend/record"
+	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/transport"
+)
+
+// ReplayResult 请求重放结果
+type ReplayResult struct {
+	Record   *record.Record  `json:"record"`   // 重放请求的记录（耗时、token用量等）
+	Original *record.Capture `json:"original"` // 原始请求的捕获内容
+	Replay   *record.Capture `json:"replay"`   // 重放请求的捕获内容
+}
+
+// ReplayRequest 将已捕获的原始请求通过完整的转换流程发送到指定渠道，返回两次结果用于对比
+func (app *App) ReplayRequest(id uint64, group, name string) (*ReplayResult, error) {
+	original, err := app.RecordMgr.GetCapture(id)
+	if err != nil {
+		return nil, err
+	}
+	if original.ClientRequest == nil {
+		return nil, fmt.Errorf("请求记录缺少原始请求内容: %d", id)
+	}
+	if original.ClientRequest.Truncated {
+		return nil, fmt.Errorf("原始请求内容已被截断，无法重放: %d", id)
+	}
+
+	ch, err := app.ChannelMgr.GetChannel(group, name)
+	if err != nil {
+		return nil, err
+	}
+	converter, err := convert.Get(ch.ConverterName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 还原客户端原始请求
+	request, err := newReplayRequest(original.ClientRequest)
+	if err != nil {
+		return nil, err
+	}
+	// 重放用于调试，不计入渠道统计
+	request = request.WithContext(statistics.Exclude(request.Context()))
+
+	slog.Info(fmt.Sprintf("[%s] 开始重放请求", ch.Name), "id", id, "group", group, "converter", ch.ConverterName)
+	trace := app.RecordMgr.Detached(group, ch, record.RequestModel(request))
+	trace.CaptureClientRequest(request)
+
+	upstream, err := converter.ConvertRequest(request, *ch)
+	if err != nil {
+		trace.Fail(http.StatusInternalServerError, err)
+		return nil, fmt.Errorf("转换请求失败: %w", err)
+	}
+	trace.CaptureUpstreamRequest(upstream)
+
+	response, err := transport.Client(ch.Outbound()).Do(upstream)
+	if err != nil {
+		trace.Fail(http.StatusBadGateway, err)
+		return nil, fmt.Errorf("请求出现错误: %w", err)
+	}
+	trace.CaptureUpstreamResponse(response)
+
+	if response.StatusCode == http.StatusOK && response.Body != nil {
+		if strings.Contains(response.Header.Get("Content-Type"), "text/event-stream") {
+			response, err = converter.ConvertStream(response, *ch)
+		} else {
+			response, err = converter.ConvertResponse(response, *ch)
+		}
+		if err != nil {
+			trace.Fail(http.StatusInternalServerError, err)
+			return nil, fmt.Errorf("转换响应失败: %w", err)
+		}
+	}
+
+	// 读取完整响应，触发统计和捕获
+	response = trace.Wrap(response)
+	if response.Body != nil {
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+	}
+
+	result, replay := trace.Result()
+	slog.Info(fmt.Sprintf("[%s] 重放请求完成", ch.Name), "id", id, "status", result.Status, "latency", result.Latency)
+	return &ReplayResult{Record: result, Original: original, Replay: replay}, nil
+}
+
+// newReplayRequest 根据捕获内容构造客户端请求
+func newReplayRequest(message *record.Message) (*http.Request, error) {
+	u, err := url.Parse(message.URL)
+	if err != nil {
+		return nil, fmt.Errorf("原始请求地址解析失败: %w", err)
+	}
+	request, err := http.NewRequest(message.Method, u.String(), bytes.NewReader([]byte(message.Body)))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range message.Header {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+	// 由 Transport 处理压缩，避免转换器读取到压缩数据
+	request.Header.Del("Accept-Encoding")
+	return request, nil
+}
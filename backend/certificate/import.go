@@ -0,0 +1,143 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const (
+	FormatPEM = "pem" // PEM 编码，可包含证书链
+	FormatDER = "der" // DER 编码，仅包含签发用的 CA 证书
+)
+
+// Import 导入自有 CA 用于签发站点证书，替换当前 CA。
+// certData 为 PEM 证书（可包含私钥和证书链）或 PKCS#12 文件；keyData 为单独的 PEM 私钥，可为空；
+// passphrase 为 PKCS#12 文件的密码。不会修改系统信任存储，自有 CA 需已被信任
+func (c *CertManager) Import(certData, keyData []byte, passphrase string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var certPEM, keyPEM []byte
+	var err error
+	if bytes.Contains(certData, []byte("-----BEGIN")) {
+		certPEM, keyPEM, err = splitPEM(append(append([]byte{}, certData...), keyData...))
+	} else {
+		certPEM, keyPEM, err = decodePKCS12(certData, passphrase)
+	}
+	if err != nil {
+		return err
+	}
+
+	cert, err := parseCA(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("导入 CA 失败: %w", err)
+	}
+	if err := checkCA(cert.Leaf); err != nil {
+		return err
+	}
+	stores := c.installedStores()
+	if err := writeCA(c.dir, certPEM, keyPEM); err != nil {
+		return err
+	}
+	setCA(cert, certPEM)
+	slog.Info("已导入 CA 证书", "subject", cert.Leaf.Subject.String(), "serial", cert.Leaf.SerialNumber.Text(16), "expires", cert.Leaf.NotAfter)
+	// 运行时信任存储中的旧 CA 替换为导入的 CA，系统信任存储由用户自行管理
+	return c.reinstallStores(stores)
+}
+
+// ImportFile 从文件导入自有 CA，keyPath 为空时从证书文件中读取私钥
+func (c *CertManager) ImportFile(certPath, keyPath, passphrase string) error {
+	certData, err := os.ReadFile(certPath)
+	if err != nil {
+		return fmt.Errorf("读取证书文件失败: %w", err)
+	}
+	var keyData []byte
+	if keyPath != "" {
+		if keyData, err = os.ReadFile(keyPath); err != nil {
+			return fmt.Errorf("读取私钥文件失败: %w", err)
+		}
+	}
+	return c.Import(certData, keyData, passphrase)
+}
+
+// ExportCertAs 按格式（pem|der）导出当前 CA 证书，PEM 包含完整证书链，DER 仅包含签发用的 CA 证书
+func (c *CertManager) ExportCertAs(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "", FormatPEM:
+		return currentPEM(), nil
+	case FormatDER:
+		current := CA()
+		if len(current.Certificate) == 0 {
+			return nil, fmt.Errorf("CA 证书未初始化")
+		}
+		return current.Certificate[0], nil
+	default:
+		return nil, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// splitPEM 从 PEM 数据中分离证书和私钥，其他类型的块被忽略
+func splitPEM(data []byte) (certPEM, keyPEM []byte, err error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch {
+		case block.Type == "CERTIFICATE":
+			certPEM = append(certPEM, pem.EncodeToMemory(block)...)
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] != "" {
+				return nil, nil, fmt.Errorf("不支持加密的 PEM 私钥，请先解密或改用 PKCS#12 文件")
+			}
+			if keyPEM != nil {
+				return nil, nil, fmt.Errorf("PEM 中包含多个私钥")
+			}
+			keyPEM = pem.EncodeToMemory(block)
+		}
+	}
+	if certPEM == nil {
+		return nil, nil, fmt.Errorf("未找到 PEM 证书")
+	}
+	if keyPEM == nil {
+		return nil, nil, fmt.Errorf("未找到 PEM 私钥")
+	}
+	return certPEM, keyPEM, nil
+}
+
+// decodePKCS12 解析 PKCS#12 文件，转换为 PEM 编码的证书链和 PKCS#8 私钥
+func decodePKCS12(data []byte, passphrase string) (certPEM, keyPEM []byte, err error) {
+	key, cert, chain, err := pkcs12.DecodeChain(data, passphrase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析 PKCS#12 文件失败: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("不支持的私钥类型: %w", err)
+	}
+	for _, c := range append([]*x509.Certificate{cert}, chain...) {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// checkCA 检查证书是否可用于签发站点证书
+func checkCA(cert *x509.Certificate) error {
+	if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("证书不允许签发其他证书: %s", cert.Subject)
+	}
+	if now := time.Now(); now.After(cert.NotAfter) || now.Before(cert.NotBefore) {
+		return fmt.Errorf("证书不在有效期内: %s 至 %s", cert.NotBefore.Format(time.DateOnly), cert.NotAfter.Format(time.DateOnly))
+	}
+	return nil
+}
@@ -1,11 +1,13 @@
 package certificate
 
 import (
+	"crypto/x509"
 	"log/slog"
 	"math/big"
 	"os"
 	"os/exec"
 	"os/user"
+	"runtime"
 	"sync"
 )
 
@@ -15,18 +17,25 @@ var installer CertInstaller = DefaultInstaller{}
 type CertInstaller interface {
 	Install(dir string, cert []byte) bool
 	Uninstall(serial *big.Int) bool
+	// Installed 检查证书是否已在系统信任存储中
+	Installed(cert *x509.Certificate) bool
 }
 
+// DefaultInstaller 不支持自动安装的平台使用，安装和卸载均返回失败，需要用户手动导入导出的证书
 type DefaultInstaller struct{}
 
 func (d DefaultInstaller) Install(string, []byte) bool {
-	//TODO implement me
-	panic("implement me")
+	slog.Error("当前系统不支持自动安装证书，请导出 CA 证书后手动安装", "os", runtime.GOOS)
+	return false
 }
 
 func (d DefaultInstaller) Uninstall(*big.Int) bool {
-	//TODO implement me
-	panic("implement me")
+	slog.Error("当前系统不支持自动卸载证书，请手动移除 CA 证书", "os", runtime.GOOS)
+	return false
+}
+
+func (d DefaultInstaller) Installed(*x509.Certificate) bool {
+	return false
 }
 
 func pathExists(path string) bool {
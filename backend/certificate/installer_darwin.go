@@ -1,6 +1,7 @@
 package certificate
 
 import (
+	"crypto/x509"
 	"encoding/xml"
 	"fmt"
 	"log/slog"
@@ -225,3 +226,9 @@ func (d DarwinCertInstaller) Uninstall(serial *big.Int) bool {
 	slog.Info("证书卸载成功", "path", path)
 	return true
 }
+
+// Installed 使用系统钥匙串的信任设置验证证书
+func (d DarwinCertInstaller) Installed(cert *x509.Certificate) bool {
+	_, err := cert.Verify(x509.VerifyOptions{KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	return err == nil
+}
@@ -2,9 +2,11 @@ package certificate
 
 import (
 	"bytes"
+	"crypto/x509"
 	"fmt"
 	"log/slog"
 	"math/big"
+	"os"
 	"strings"
 )
 
@@ -89,3 +91,15 @@ func (l LinuxCertInstaller) Uninstall(serial *big.Int) bool {
 
 	return true
 }
+
+// Installed 检查系统信任目录中的证书文件是否包含该证书
+func (l LinuxCertInstaller) Installed(cert *x509.Certificate) bool {
+	if SystemTrustCommand == nil {
+		return false
+	}
+	data, err := os.ReadFile(fmt.Sprintf(SystemTrustFilename, "chameleon_proxy"))
+	if err != nil {
+		return false
+	}
+	return containsCert(data, cert)
+}
@@ -1,6 +1,7 @@
 package certificate
 
 import (
+	"bytes"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
@@ -18,6 +19,7 @@ var (
 	procCertDeleteCertificateFromStore   = modcrypt32.NewProc("CertDeleteCertificateFromStore")
 	procCertDuplicateCertificateContext  = modcrypt32.NewProc("CertDuplicateCertificateContext")
 	procCertEnumCertificatesInStore      = modcrypt32.NewProc("CertEnumCertificatesInStore")
+	procCertFreeCertificateContext       = modcrypt32.NewProc("CertFreeCertificateContext")
 	procCertOpenSystemStoreW             = modcrypt32.NewProc("CertOpenSystemStoreW")
 )
 
@@ -72,6 +74,20 @@ func (w WindowsCertInstaller) Uninstall(serial *big.Int) bool {
 	return true
 }
 
+// Installed 检查根证书存储中是否存在相同的证书
+func (w WindowsCertInstaller) Installed(cert *x509.Certificate) bool {
+	store, err := openWindowsRootStore()
+	if err != nil {
+		return false
+	}
+	defer store.close()
+	found, err := store.hasCert(cert.Raw)
+	if err != nil {
+		slog.Debug("查找证书失败", "err", err.Error())
+	}
+	return found
+}
+
 func openWindowsRootStore() (windowsRootStore, error) {
 	rootStr, err := syscall.UTF16PtrFromString("ROOT")
 	if err != nil {
@@ -139,3 +155,23 @@ func (w windowsRootStore) deleteCertsWithSerial(serial *big.Int) (bool, error) {
 	}
 	return deletedAny, nil
 }
+
+func (w windowsRootStore) hasCert(raw []byte) (bool, error) {
+	var cert *syscall.CertContext
+	for {
+		certPtr, _, err := procCertEnumCertificatesInStore.Call(uintptr(w), uintptr(unsafe.Pointer(cert)))
+		if cert = (*syscall.CertContext)(unsafe.Pointer(certPtr)); cert == nil {
+			var errno syscall.Errno
+			if errors.As(err, &errno) && errno == 0x80092004 {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed enumerating certs: %v", err)
+		}
+		certBytes := (*[1 << 20]byte)(unsafe.Pointer(cert.EncodedCert))[:cert.Length]
+		if bytes.Equal(certBytes, raw) {
+			// 提前结束枚举时需释放当前上下文
+			procCertFreeCertificateContext.Call(uintptr(unsafe.Pointer(cert)))
+			return true, nil
+		}
+	}
+}
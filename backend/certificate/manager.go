@@ -1,8 +1,17 @@
 package certificate
 
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+)
+
 type CertManager struct {
 	dir       string
 	installer CertInstaller
+	mu        sync.Mutex
 }
 
 type Pair struct {
@@ -12,14 +21,84 @@ type Pair struct {
 	KeyPEM   []byte
 }
 
+// NewManager 创建证书管理器，加载数据目录中的 CA，首次运行时生成本机专用的 CA
 func NewManager(dataDir string) *CertManager {
+	generated, err := loadCA(dataDir)
+	if err != nil {
+		slog.Error("加载 CA 证书失败", "dir", dataDir, "error", err)
+	} else if generated {
+		slog.Warn("已生成本机专用的 CA 证书，请重新安装证书；旧版本安装过内置证书时请将其移除", "dir", dataDir)
+	}
 	return &CertManager{dir: dataDir, installer: installer}
 }
 
+// ConfigureCache 配置站点证书缓存，persist 为 true 时证书保存到数据目录的 certs 下
+func (c *CertManager) ConfigureCache(keyType string, capacity int, persist bool) {
+	opts := CacheOptions{KeyType: keyType, Capacity: capacity}
+	if persist {
+		opts.Dir = filepath.Join(c.dir, leafDir)
+	}
+	Store.Configure(opts)
+}
+
 func (c *CertManager) Install() bool {
-	return c.installer.Install(c.dir, cert)
+	return c.installer.Install(c.dir, currentPEM())
 }
 
 func (c *CertManager) Uninstall() bool {
-	return c.installer.Uninstall(CA.Leaf.SerialNumber)
+	leaf := CA().Leaf
+	if leaf == nil {
+		return false
+	}
+	return c.installer.Uninstall(leaf.SerialNumber)
+}
+
+// UninstallLegacy 从系统信任存储中移除旧版本内置的公共 CA 证书
+func (c *CertManager) UninstallLegacy() bool {
+	return c.installer.Uninstall(legacySerial)
+}
+
+// ExportCert 导出 CA 证书（PEM格式），用于手动导入到其他设备或应用
+func (c *CertManager) ExportCert() []byte {
+	return currentPEM()
+}
+
+// Rotate 使用相同的密钥算法生成新的 CA，替换并重新安装到系统信任存储
+func (c *CertManager) Rotate() error {
+	return c.Regenerate("")
+}
+
+// Regenerate 使用指定算法（ecdsa|rsa，为空时与当前 CA 相同）生成新的 CA，
+// 从系统信任存储中移除旧 CA 后安装新 CA，已签发的站点证书缓存同时失效
+func (c *CertManager) Regenerate(algorithm string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old := CA()
+	algorithm = cmp.Or(algorithm, algorithmOf(old))
+	certPEM, keyPEM, err := generateCA(algorithm)
+	if err != nil {
+		return err
+	}
+	cert, err := parseCA(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+
+	// 其他信任存储中已安装旧 CA 的，更换后重新安装
+	stores := c.installedStores()
+	// 需在替换证书文件前移除，部分平台按证书文件卸载
+	if old.Leaf != nil && !c.installer.Uninstall(old.Leaf.SerialNumber) {
+		slog.Warn("移除旧 CA 证书失败，可能未安装或需要手动移除", "serial", old.Leaf.SerialNumber.Text(16))
+	}
+	if err := writeCA(c.dir, certPEM, keyPEM); err != nil {
+		return err
+	}
+	setCA(cert, certPEM)
+	slog.Info("已生成新的 CA 证书", "algorithm", algorithm, "serial", cert.Leaf.SerialNumber.Text(16), "expires", cert.Leaf.NotAfter)
+
+	if !c.installer.Install(c.dir, certPEM) {
+		return fmt.Errorf("新 CA 证书安装失败，请手动安装")
+	}
+	return c.reinstallStores(stores)
 }
@@ -36,7 +36,16 @@ type CounterEncryptorRand struct {
 	ix      int
 }
 
+// SignHost 使用 CA 为域名签发 ECDSA P-256 站点证书
 func SignHost(ca tls.Certificate, hosts []string) (cert *tls.Certificate, err error) {
+	return SignHostWithKey(ca, hosts, AlgorithmECDSA)
+}
+
+// SignHostWithKey 使用 CA 为域名签发站点证书，keyType 为站点证书的密钥算法 ecdsa|rsa，与 CA 的密钥算法无关
+func SignHostWithKey(ca tls.Certificate, hosts []string, keyType string) (cert *tls.Certificate, err error) {
+	if len(ca.Certificate) == 0 {
+		return nil, errors.New("CA 证书未初始化")
+	}
 	// 使用提供的 CA 生成证书，使用已解析的叶证书（如果存在）。
 	x509ca := ca.Leaf
 	if x509ca == nil {
@@ -48,6 +57,10 @@ func SignHost(ca tls.Certificate, hosts []string) (cert *tls.Certificate, err er
 	now := time.Now()
 	start := now.Add(-30 * 24 * time.Hour) // -30 days
 	end := now.Add(365 * 24 * time.Hour)   // 365 days
+	// 站点证书不能晚于 CA 过期，否则部分客户端会拒绝证书链
+	if end.After(x509ca.NotAfter) {
+		end = x509ca.NotAfter
+	}
 
 	// 始终生成正 int 值（当第一位为 0 时，不启用二补码）
 	generated := rand.Uint64()
@@ -63,7 +76,7 @@ func SignHost(ca tls.Certificate, hosts []string) (cert *tls.Certificate, err er
 		NotBefore: start,
 		NotAfter:  end,
 
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
 	}
@@ -83,21 +96,19 @@ func SignHost(ca tls.Certificate, hosts []string) (cert *tls.Certificate, err er
 	}
 
 	var certpriv crypto.Signer
-	switch ca.PrivateKey.(type) {
-	case *rsa.PrivateKey:
-		if certpriv, err = rsa.GenerateKey(&csprng, 2048); err != nil {
-			return nil, err
-		}
-	case *ecdsa.PrivateKey:
+	switch keyType {
+	case "", AlgorithmECDSA:
 		if certpriv, err = ecdsa.GenerateKey(elliptic.P256(), &csprng); err != nil {
 			return nil, err
 		}
-	case ed25519.PrivateKey:
-		if _, certpriv, err = ed25519.GenerateKey(&csprng); err != nil {
+	case AlgorithmRSA:
+		if certpriv, err = rsa.GenerateKey(&csprng, 2048); err != nil {
 			return nil, err
 		}
+		// RSA 密钥交换需要 KeyEncipherment
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
 	default:
-		return nil, fmt.Errorf("unsupported key type %T", ca.PrivateKey)
+		return nil, fmt.Errorf("不支持的站点证书密钥算法: %s", keyType)
 	}
 
 	derBytes, err := x509.CreateCertificate(&csprng, &template, x509ca, certpriv.Public(), ca.PrivateKey)
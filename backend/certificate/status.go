@@ -0,0 +1,81 @@
+package certificate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// expiryWarning CA 或站点证书剩余有效期少于该时长时提示即将过期
+const expiryWarning = 30 * 24 * time.Hour
+
+// CertStatus 当前 CA 的实际状态，安装情况由各信任存储实时检查，而非配置中记录的标记
+type CertStatus struct {
+	Subject      string             `json:"subject"`
+	Serial       string             `json:"serial"`      // 十六进制序列号
+	Fingerprint  string             `json:"fingerprint"` // 根证书 SHA-256 指纹
+	NotBefore    time.Time          `json:"notBefore"`
+	NotAfter     time.Time          `json:"notAfter"`
+	Expired      bool               `json:"expired"`
+	ExpiringSoon bool               `json:"expiringSoon"` // 30 天内过期
+	Installed    bool               `json:"installed"`    // 是否已被系统信任
+	Error        string             `json:"error"`        // CA 未初始化等无法检查的原因
+	Stores       []TrustStoreStatus `json:"stores"`
+	Leaves       []LeafStatus       `json:"leaves"` // 已缓存的站点证书
+}
+
+// LeafStatus 缓存的站点证书状态
+type LeafStatus struct {
+	Host         string    `json:"host"`
+	NotAfter     time.Time `json:"notAfter"`
+	Expired      bool      `json:"expired"`
+	ExpiringSoon bool      `json:"expiringSoon"`
+}
+
+// Status 检查当前 CA 是否真正被各信任存储信任（按证书指纹比对），并报告 CA 及缓存站点证书的有效期
+func (c *CertManager) Status() CertStatus {
+	anchor := trustAnchor()
+	if anchor == nil {
+		return CertStatus{Error: "CA 证书未初始化", Stores: c.TrustStores()}
+	}
+	now := time.Now()
+	fingerprint := sha256.Sum256(anchor.Raw)
+	status := CertStatus{
+		Subject:     anchor.Subject.String(),
+		Serial:      anchor.SerialNumber.Text(16),
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+		NotBefore:   anchor.NotBefore,
+		NotAfter:    anchor.NotAfter,
+		Stores:      c.TrustStores(),
+	}
+	// 导入的中间 CA 以签发证书的有效期为准
+	if leaf := CA().Leaf; leaf != nil && leaf.NotAfter.Before(status.NotAfter) {
+		status.NotAfter = leaf.NotAfter
+	}
+	status.Expired, status.ExpiringSoon = expiry(status.NotAfter, now)
+	for _, store := range status.Stores {
+		if store.Name == StoreSystem {
+			status.Installed = store.Installed
+			if !store.Available {
+				status.Error = store.Detail
+			}
+		}
+	}
+
+	for host, cert := range Store.Leaves() {
+		leaf := LeafStatus{Host: host, NotAfter: cert.NotAfter}
+		leaf.Expired, leaf.ExpiringSoon = expiry(cert.NotAfter, now)
+		status.Leaves = append(status.Leaves, leaf)
+	}
+	sort.Slice(status.Leaves, func(i, j int) bool { return status.Leaves[i].Host < status.Leaves[j].Host })
+	return status
+}
+
+// expiry 判断证书是否已过期或即将过期
+func expiry(notAfter, now time.Time) (expired, soon bool) {
+	if now.After(notAfter) {
+		return true, false
+	}
+	return false, notAfter.Sub(now) < expiryWarning
+}
@@ -1,33 +1,295 @@
 package certificate
 
 import (
+	"container/list"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
+const (
+	defaultCacheSize = 1000                // 默认最多缓存的站点证书数量
+	renewBefore      = 30 * 24 * time.Hour // 站点证书剩余有效期少于该时长时重新签发
+)
+
+// CacheOptions 站点证书缓存配置
+type CacheOptions struct {
+	KeyType  string // 站点证书密钥算法 ecdsa|rsa，为空时使用 ecdsa
+	Capacity int    // 内存中最多缓存的证书数量，0 使用默认值
+	Dir      string // 持久化目录，为空时不保存到磁盘
+}
+
+// Storage 站点证书缓存，按最近使用淘汰，同一域名并发请求只签发一次
 type Storage struct {
-	Cache map[string]*tls.Certificate
-	mu    sync.Mutex
+	opts    CacheOptions
+	entries map[string]*list.Element // 域名 -> lru 中的 *cacheEntry
+	lru     *list.List               // 最近使用的在前
+	calls   map[string]*signCall     // 正在签发的域名
+	gen     uint64                   // 清空缓存时递增，丢弃清空前开始的签发结果
+	mu      sync.Mutex
+}
+
+type cacheEntry struct {
+	host string
+	cert *tls.Certificate
 }
 
-var Store = &Storage{Cache: make(map[string]*tls.Certificate)}
+// signCall 正在进行的签发，等待者在 done 关闭后读取结果
+type signCall struct {
+	done chan struct{}
+	cert *tls.Certificate
+	err  error
+}
+
+var Store = NewStorage(CacheOptions{})
+
+// NewStorage 创建站点证书缓存
+func NewStorage(opts CacheOptions) *Storage {
+	s := &Storage{entries: make(map[string]*list.Element), lru: list.New(), calls: make(map[string]*signCall)}
+	s.Configure(opts)
+	return s
+}
+
+// Configure 更新缓存配置，容量缩小时淘汰多余的证书，密钥算法变化时清空缓存
+func (s *Storage) Configure(opts CacheOptions) {
+	if opts.KeyType == "" {
+		opts.KeyType = AlgorithmECDSA
+	}
+	if opts.Capacity <= 0 {
+		opts.Capacity = defaultCacheSize
+	}
 
-func (s *Storage) Fetch(hostname string, gen func() (*tls.Certificate, error)) (*tls.Certificate, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.opts.KeyType != "" && s.opts.KeyType != opts.KeyType {
+		s.reset()
+	}
+	s.opts = opts
+	for s.lru.Len() > s.opts.Capacity {
+		s.evict()
+	}
+}
+
+// Certificate 获取域名的站点证书，未缓存或即将过期时使用当前 CA 签发
+func (s *Storage) Certificate(hostname string) (*tls.Certificate, error) {
+	return s.Fetch(hostname, func() (*tls.Certificate, error) {
+		s.mu.Lock()
+		keyType := s.opts.KeyType
+		s.mu.Unlock()
+		return SignHostWithKey(CA(), []string{hostname}, keyType)
+	})
+}
 
-	if cert, ex := s.Cache[hostname]; ex {
-		slog.Debug("[cert-store] 证书缓存命中", "host", hostname)
-		return cert, nil
+// Fetch 从缓存获取证书，不存在或即将过期时调用 gen 生成；签发在锁外进行，同一域名的并发请求共享结果，
+// 签发期间缓存被清空时结果不再缓存和保存
+func (s *Storage) Fetch(hostname string, gen func() (*tls.Certificate, error)) (*tls.Certificate, error) {
+	expiry := caExpiry()
+	s.mu.Lock()
+	if elem, ok := s.entries[hostname]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if fresh(entry.cert, expiry) {
+			s.lru.MoveToFront(elem)
+			s.mu.Unlock()
+			slog.Debug("[cert-store] 证书缓存命中", "host", hostname)
+			return entry.cert, nil
+		}
+		slog.Debug("[cert-store] 证书即将过期，重新签发", "host", hostname, "expires", entry.cert.Leaf.NotAfter)
+	}
+	if call, ok := s.calls[hostname]; ok {
+		s.mu.Unlock()
+		<-call.done
+		return call.cert, call.err
 	}
+	call := &signCall{done: make(chan struct{})}
+	s.calls[hostname] = call
+	generation := s.gen
+	dir := s.opts.Dir
+	s.mu.Unlock()
 
-	if cert, err := gen(); err != nil {
-		slog.Debug("[cert-store] 证书生成失败", "host", hostname)
-		return nil, err
+	loaded := false
+	call.cert, call.err = s.load(dir, hostname)
+	if call.cert != nil {
+		loaded = true
+	} else if call.cert, call.err = gen(); call.err != nil {
+		slog.Debug("[cert-store] 证书生成失败", "host", hostname, "error", call.err)
 	} else {
 		slog.Debug("[cert-store] 证书生成成功", "host", hostname)
-		s.Cache[hostname] = cert
-		return cert, nil
 	}
+
+	s.mu.Lock()
+	if s.calls[hostname] == call {
+		delete(s.calls, hostname)
+	}
+	switch {
+	case call.err != nil:
+	case s.gen != generation:
+		slog.Debug("[cert-store] 签发期间缓存已清空，丢弃证书", "host", hostname)
+	default:
+		// 持有锁时保存，避免与 Clear 删除文件交错
+		if !loaded {
+			s.save(dir, hostname, call.cert)
+		}
+		s.put(hostname, call.cert)
+	}
+	s.mu.Unlock()
+	close(call.done)
+	return call.cert, call.err
+}
+
+// Clear 清空证书缓存及持久化的证书，CA 更换后旧证书不再可用
+func (s *Storage) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+}
+
+// reset 清空缓存和持久化的证书，正在进行的签发结果将被丢弃，调用方需持有锁
+func (s *Storage) reset() {
+	s.entries = make(map[string]*list.Element)
+	s.lru.Init()
+	s.calls = make(map[string]*signCall)
+	s.gen++
+	s.removeFiles()
+}
+
+// Leaves 获取缓存中站点证书的快照
+func (s *Storage) Leaves() map[string]*x509.Certificate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leaves := make(map[string]*x509.Certificate, len(s.entries))
+	for host, elem := range s.entries {
+		if cert := elem.Value.(*cacheEntry).cert; cert.Leaf != nil {
+			leaves[host] = cert.Leaf
+		}
+	}
+	return leaves
+}
+
+// put 加入缓存，超出容量时淘汰最久未使用的证书，调用方需持有锁
+func (s *Storage) put(hostname string, cert *tls.Certificate) {
+	if elem, ok := s.entries[hostname]; ok {
+		elem.Value.(*cacheEntry).cert = cert
+		s.lru.MoveToFront(elem)
+		return
+	}
+	s.entries[hostname] = s.lru.PushFront(&cacheEntry{host: hostname, cert: cert})
+	for s.lru.Len() > s.opts.Capacity {
+		s.evict()
+	}
+}
+
+// evict 淘汰最久未使用的证书，磁盘中的证书保留，调用方需持有锁
+func (s *Storage) evict() {
+	elem := s.lru.Back()
+	if elem == nil {
+		return
+	}
+	s.lru.Remove(elem)
+	delete(s.entries, elem.Value.(*cacheEntry).host)
+}
+
+// load 从持久化目录读取证书，证书不是由当前 CA 签发或即将过期时忽略
+func (s *Storage) load(dir, hostname string) (*tls.Certificate, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, leafFile(hostname)))
+	if err != nil {
+		return nil, nil
+	}
+	current := CA()
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil || current.Leaf == nil {
+		return nil, nil
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, nil
+		}
+	}
+	if cert.Leaf.CheckSignatureFrom(current.Leaf) != nil || !fresh(&cert, current.Leaf.NotAfter) {
+		return nil, nil
+	}
+	// 证书链使用当前 CA，文件中只保存站点证书
+	cert.Certificate = append(cert.Certificate[:1:1], current.Certificate...)
+	slog.Debug("[cert-store] 从磁盘加载证书", "host", hostname)
+	return &cert, nil
+}
+
+// save 将站点证书和私钥保存到持久化目录，失败时只记录日志
+func (s *Storage) save(dir, hostname string, cert *tls.Certificate) {
+	if dir == "" {
+		return
+	}
+	if err := writeLeaf(dir, hostname, cert); err != nil {
+		slog.Warn("[cert-store] 保存证书失败", "host", hostname, "error", err)
+	}
+}
+
+// removeFiles 删除持久化的证书，调用方需持有锁
+func (s *Storage) removeFiles() {
+	if s.opts.Dir == "" {
+		return
+	}
+	files, _ := filepath.Glob(filepath.Join(s.opts.Dir, "*.pem"))
+	for _, file := range files {
+		_ = os.Remove(file)
+	}
+}
+
+// writeLeaf 以 0600 权限保存站点证书和 PKCS#8 私钥
+func writeLeaf(dir, hostname string, cert *tls.Certificate) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})...)
+
+	path := filepath.Join(dir, leafFile(hostname))
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return fmt.Errorf("保存 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// leafFile 域名对应的证书文件名，非域名字符替换为下划线
+func leafFile(hostname string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, hostname) + ".pem"
+}
+
+// fresh 证书剩余有效期是否足够，不足时需要重新签发；
+// 有效期已达到 CA 的到期时间（caExpiry）时重新签发也无法延长，视为有效
+func fresh(cert *tls.Certificate, caExpiry time.Time) bool {
+	if cert.Leaf == nil || time.Until(cert.Leaf.NotAfter) > renewBefore {
+		return true
+	}
+	return !caExpiry.IsZero() && !cert.Leaf.NotAfter.Before(caExpiry)
+}
+
+// caExpiry 当前 CA 的到期时间，CA 未初始化时为零值
+func caExpiry() time.Time {
+	if leaf := CA().Leaf; leaf != nil {
+		return leaf.NotAfter
+	}
+	return time.Time{}
 }
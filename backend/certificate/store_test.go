@@ -0,0 +1,218 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testCA 创建在 notAfter 过期的自签名 CA
+func testCA(t *testing.T, notAfter time.Time) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// expiring 生成在 d 之后过期的证书
+func expiring(d time.Duration) func() (*tls.Certificate, error) {
+	return func() (*tls.Certificate, error) {
+		return &tls.Certificate{Leaf: &x509.Certificate{NotAfter: time.Now().Add(d)}}, nil
+	}
+}
+
+func TestFetchEvictsLeastRecentlyUsed(t *testing.T) {
+	s := NewStorage(CacheOptions{Capacity: 2})
+	for _, host := range []string{"a.com", "b.com", "a.com", "c.com"} {
+		if _, err := s.Fetch(host, expiring(365*24*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	leaves := s.Leaves()
+	if len(leaves) != 2 || leaves["a.com"] == nil || leaves["c.com"] == nil {
+		t.Errorf("缓存的域名 = %v, want a.com c.com", leaves)
+	}
+
+	s.Configure(CacheOptions{Capacity: 1})
+	if leaves := s.Leaves(); len(leaves) != 1 || leaves["c.com"] == nil {
+		t.Errorf("缩小容量后缓存的域名 = %v, want c.com", leaves)
+	}
+}
+
+func TestFetchRenewsExpiringCertificate(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining time.Duration
+		wantCalls int32
+	}{
+		{name: "有效期充足", remaining: 365 * 24 * time.Hour, wantCalls: 1},
+		{name: "即将过期", remaining: 10 * 24 * time.Hour, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStorage(CacheOptions{})
+			var calls atomic.Int32
+			gen := func() (*tls.Certificate, error) {
+				calls.Add(1)
+				return expiring(tt.remaining)()
+			}
+			for range 2 {
+				if _, err := s.Fetch("a.com", gen); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("签发次数 = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFetchSignsOncePerHost(t *testing.T) {
+	s := NewStorage(CacheOptions{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	gen := func() (*tls.Certificate, error) {
+		calls.Add(1)
+		<-release
+		return expiring(365 * 24 * time.Hour)()
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	certs := make([]*tls.Certificate, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			certs[i], _ = s.Fetch("a.com", gen)
+		}()
+	}
+	// 等待所有请求进入等待状态后再完成签发
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("签发次数 = %d, want 1", got)
+	}
+	for i, cert := range certs {
+		if cert == nil || cert != certs[0] {
+			t.Fatalf("certs[%d] 与其他请求的结果不同", i)
+		}
+	}
+}
+
+// 签发期间清空缓存，旧的签发结果不应写入缓存和磁盘
+func TestFetchDiscardsResultAfterClear(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStorage(CacheOptions{Dir: dir})
+	ca := testCA(t, time.Now().Add(10*365*24*time.Hour))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := s.Fetch("a.com", func() (*tls.Certificate, error) {
+			close(started)
+			<-release
+			return SignHost(ca, []string{"a.com"})
+		})
+		done <- err
+	}()
+	<-started
+	s.Clear()
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if leaves := s.Leaves(); len(leaves) != 0 {
+		t.Errorf("清空后缓存的域名 = %v, want 无", leaves)
+	}
+	if _, err := os.Stat(filepath.Join(dir, leafFile("a.com"))); !os.IsNotExist(err) {
+		t.Errorf("清空后证书文件仍被保存: %v", err)
+	}
+
+	// 清空后重新请求会重新签发并保存
+	if _, err := s.Fetch("a.com", func() (*tls.Certificate, error) { return SignHost(ca, []string{"a.com"}) }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, leafFile("a.com"))); err != nil {
+		t.Errorf("证书文件未保存: %v", err)
+	}
+}
+
+func TestSignHostCapsNotAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		caExpiry time.Duration
+		capped   bool
+	}{
+		{name: "CA 有效期充足", caExpiry: 10 * 365 * 24 * time.Hour},
+		{name: "CA 即将过期", caExpiry: 30 * 24 * time.Hour, capped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca := testCA(t, time.Now().Add(tt.caExpiry))
+			cert, err := SignHostWithKey(ca, []string{"a.com"}, AlgorithmECDSA)
+			if err != nil {
+				t.Fatal(err)
+			}
+			notAfter := cert.Leaf.NotAfter
+			if notAfter.After(ca.Leaf.NotAfter) {
+				t.Errorf("NotAfter = %v, 晚于 CA 的 %v", notAfter, ca.Leaf.NotAfter)
+			}
+			if got := notAfter.Equal(ca.Leaf.NotAfter); got != tt.capped {
+				t.Errorf("NotAfter = %v, CA NotAfter = %v, capped = %v, want %v", notAfter, ca.Leaf.NotAfter, got, tt.capped)
+			}
+			if err := cert.Leaf.CheckSignatureFrom(ca.Leaf); err != nil {
+				t.Errorf("CheckSignatureFrom() error = %v", err)
+			}
+		})
+	}
+}
+
+// 有效期已到 CA 上限的证书不再反复签发
+func TestFreshAtCAExpiry(t *testing.T) {
+	caExpiry := time.Now().Add(10 * 24 * time.Hour).Truncate(time.Second)
+	cert := &tls.Certificate{Leaf: &x509.Certificate{NotAfter: caExpiry}}
+	if !fresh(cert, caExpiry) {
+		t.Error("fresh() = false, want true")
+	}
+	if fresh(cert, caExpiry.Add(365*24*time.Hour)) {
+		t.Error("CA 有效期充足时 fresh() = true, want false")
+	}
+}
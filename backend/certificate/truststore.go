@@ -0,0 +1,218 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+const (
+	StoreSystem = "system" // 操作系统信任存储
+	StoreNSS    = "nss"    // Firefox/Chrome 使用的 NSS 数据库
+	StoreJava   = "java"   // Java cacerts
+	StoreEnv    = "env"    // Node/Python/OpenSSL 环境变量
+
+	trustNickname = "chameleon_proxy" // 在 NSS、Java 信任存储中使用的证书别名
+)
+
+// TrustStoreStatus 信任存储的安装状态
+type TrustStoreStatus struct {
+	Name      string `json:"name"`      // 存储名称：system|nss|java|env
+	Available bool   `json:"available"` // 本机是否存在该存储及所需的工具
+	Installed bool   `json:"installed"` // 当前 CA 是否已安装
+	Detail    string `json:"detail"`    // 存储位置或不可用的原因
+}
+
+// trustStore 可安装 CA 的信任存储
+type trustStore interface {
+	name() string
+	// available 检查存储是否可用，返回位置说明或不可用的原因
+	available() (bool, string)
+	installed(cert *x509.Certificate) bool
+	install(certPath string, cert *x509.Certificate) error
+	uninstall(cert *x509.Certificate) error
+}
+
+// trustStores 按名称获取信任存储，名称为空时返回全部
+func (c *CertManager) trustStores(names ...string) ([]trustStore, error) {
+	all := []trustStore{systemStore{c}, nssStore{}, javaStore{}, envStore{c.dir}}
+	if len(names) == 0 {
+		return all, nil
+	}
+	var stores []trustStore
+	for _, name := range names {
+		found := false
+		for _, store := range all {
+			if store.name() == name {
+				stores, found = append(stores, store), true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("未知的信任存储: %s", name)
+		}
+	}
+	return stores, nil
+}
+
+// TrustStores 获取各信任存储中当前 CA 的安装状态
+func (c *CertManager) TrustStores() []TrustStoreStatus {
+	anchor := trustAnchor()
+	stores, _ := c.trustStores()
+	statuses := make([]TrustStoreStatus, 0, len(stores))
+	for _, store := range stores {
+		ok, detail := store.available()
+		status := TrustStoreStatus{Name: store.name(), Available: ok, Detail: detail}
+		if ok && anchor != nil {
+			status.Installed = store.installed(anchor)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// InstallStores 将当前 CA 安装到指定的信任存储，不可用的存储返回错误
+func (c *CertManager) InstallStores(names ...string) error {
+	return c.eachStore(names, func(store trustStore, certPath string, anchor *x509.Certificate) error {
+		return store.install(certPath, anchor)
+	})
+}
+
+// UninstallStores 从指定的信任存储中移除当前 CA
+func (c *CertManager) UninstallStores(names ...string) error {
+	return c.eachStore(names, func(store trustStore, _ string, anchor *x509.Certificate) error {
+		return store.uninstall(anchor)
+	})
+}
+
+// eachStore 对指定的存储依次执行操作，汇总所有错误
+func (c *CertManager) eachStore(names []string, fn func(store trustStore, certPath string, anchor *x509.Certificate) error) error {
+	stores, err := c.trustStores(names...)
+	if err != nil {
+		return err
+	}
+	anchor := trustAnchor()
+	if anchor == nil {
+		return fmt.Errorf("CA 证书未初始化")
+	}
+	certPath, cleanup, err := writeAnchor(anchor)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	var errs []error
+	for _, store := range stores {
+		if ok, detail := store.available(); !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", store.name(), detail))
+			continue
+		}
+		if err := fn(store, certPath, anchor); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", store.name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// installedStores 获取已安装当前 CA 的非系统信任存储，更换 CA 时需要重新安装
+func (c *CertManager) installedStores() []string {
+	var names []string
+	for _, status := range c.TrustStores() {
+		if status.Name != StoreSystem && status.Installed {
+			names = append(names, status.Name)
+		}
+	}
+	return names
+}
+
+// reinstallStores 更换 CA 后将新 CA 安装到原先已安装的存储
+func (c *CertManager) reinstallStores(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	slog.Info("重新安装 CA 到信任存储", "stores", names)
+	return c.InstallStores(names...)
+}
+
+// trustAnchor 获取需要被信任的证书，导入的 CA 带有证书链时为链末端的根证书
+func trustAnchor() *x509.Certificate {
+	current := CA()
+	if len(current.Certificate) == 0 {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(current.Certificate[len(current.Certificate)-1])
+	if err != nil {
+		return nil
+	}
+	return cert
+}
+
+// writeAnchor 将根证书写入临时文件供外部工具读取
+func writeAnchor(cert *x509.Certificate) (string, func(), error) {
+	file, err := os.CreateTemp("", "chameleon-ca-*.pem")
+	if err != nil {
+		return "", nil, fmt.Errorf("创建临时证书文件失败: %w", err)
+	}
+	_, err = file.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", nil, fmt.Errorf("写入临时证书文件失败: %w", err)
+	}
+	return file.Name(), func() { _ = os.Remove(file.Name()) }, nil
+}
+
+// containsCert 检查 PEM 数据中是否包含该证书
+func containsCert(data []byte, cert *x509.Certificate) bool {
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			return false
+		}
+		if block.Type == "CERTIFICATE" && bytes.Equal(block.Bytes, cert.Raw) {
+			return true
+		}
+	}
+}
+
+// systemStore 操作系统信任存储，由各平台的安装器实现
+type systemStore struct {
+	c *CertManager
+}
+
+func (s systemStore) name() string { return StoreSystem }
+
+func (s systemStore) available() (bool, string) {
+	if _, ok := s.c.installer.(DefaultInstaller); ok {
+		return false, "当前系统不支持自动安装"
+	}
+	return true, ""
+}
+
+func (s systemStore) installed(cert *x509.Certificate) bool {
+	return s.c.installer.Installed(cert)
+}
+
+func (s systemStore) install(certPath string, _ *x509.Certificate) error {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return err
+	}
+	if !s.c.installer.Install(s.c.dir, data) {
+		return fmt.Errorf("安装失败，可能需要管理员权限")
+	}
+	return nil
+}
+
+func (s systemStore) uninstall(cert *x509.Certificate) error {
+	if !s.c.installer.Uninstall(cert.SerialNumber) {
+		return fmt.Errorf("卸载失败，可能需要管理员权限")
+	}
+	slog.Info("已从系统信任存储移除 CA 证书")
+	return nil
+}
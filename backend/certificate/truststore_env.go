@@ -0,0 +1,108 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	bundleFile   = "ca-bundle.pem"     // 系统根证书和 CA 合并后的证书包
+	envShellFile = "chameleon-env.sh"  // POSIX shell 环境变量片段
+	envPSFile    = "chameleon-env.ps1" // PowerShell 环境变量片段
+)
+
+// systemBundles 常见的系统根证书包位置
+var systemBundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+// envStore 生成证书包和环境变量片段，供 Node、Python、OpenSSL 等不读取系统信任存储的运行时使用
+type envStore struct {
+	dir string
+}
+
+func (envStore) name() string { return StoreEnv }
+
+func (s envStore) available() (bool, string) {
+	return true, s.scriptPath()
+}
+
+// scriptPath 当前平台使用的环境变量片段路径
+func (s envStore) scriptPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(s.dir, envPSFile)
+	}
+	return filepath.Join(s.dir, envShellFile)
+}
+
+func (s envStore) installed(cert *x509.Certificate) bool {
+	if !pathExists(s.scriptPath()) {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, bundleFile))
+	return err == nil && containsCert(data, cert)
+}
+
+// install 写入证书包和环境变量片段。NODE_EXTRA_CA_CERTS 在 Node 默认根证书之外追加证书；
+// REQUESTS_CA_BUNDLE、SSL_CERT_FILE 会替换默认根证书，只有找到系统根证书包时才设置
+func (s envStore) install(_ string, cert *x509.Certificate) error {
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	var system string
+	for _, path := range systemBundles {
+		if data, err := os.ReadFile(path); err == nil {
+			system = path
+			bundle = append(append(bytes.TrimRight(data, "\n"), '\n'), bundle...)
+			break
+		}
+	}
+	bundlePath := filepath.Join(s.dir, bundleFile)
+	if err := os.WriteFile(bundlePath, bundle, 0644); err != nil {
+		return fmt.Errorf("写入证书包失败: %w", err)
+	}
+
+	vars := [][2]string{{"NODE_EXTRA_CA_CERTS", bundlePath}}
+	if system != "" {
+		vars = append(vars, [2]string{"REQUESTS_CA_BUNDLE", bundlePath}, [2]string{"SSL_CERT_FILE", bundlePath})
+	}
+	var script strings.Builder
+	if runtime.GOOS == "windows" {
+		script.WriteString("# Chameleon CA，在 PowerShell 中执行: . \"" + s.scriptPath() + "\"\n")
+		for _, v := range vars {
+			fmt.Fprintf(&script, "$env:%s = %q\n", v[0], v[1])
+		}
+	} else {
+		script.WriteString("# Chameleon CA，在 shell 配置文件中添加: . \"" + s.scriptPath() + "\"\n")
+		if system != "" {
+			script.WriteString("# 证书包由 " + system + " 和 Chameleon CA 合并生成，系统根证书更新后需重新安装\n")
+		}
+		for _, v := range vars {
+			fmt.Fprintf(&script, "export %s=%q\n", v[0], v[1])
+		}
+	}
+	if err := os.WriteFile(s.scriptPath(), []byte(script.String()), 0644); err != nil {
+		return fmt.Errorf("写入环境变量片段失败: %w", err)
+	}
+	return nil
+}
+
+func (s envStore) uninstall(*x509.Certificate) error {
+	var errs []error
+	for _, name := range []string{bundleFile, envShellFile, envPSFile} {
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
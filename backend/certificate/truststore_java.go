@@ -0,0 +1,104 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// javaStorePass cacerts 的默认密码
+const javaStorePass = "changeit"
+
+// javaStore Java 运行时的 cacerts，通过 keytool 管理，需要设置 JAVA_HOME
+type javaStore struct{}
+
+func (javaStore) name() string { return StoreJava }
+
+// javaPaths 根据 JAVA_HOME 查找 keytool 和 cacerts
+func javaPaths() (keytool, cacerts string) {
+	home := os.Getenv("JAVA_HOME")
+	if home == "" {
+		return "", ""
+	}
+	keytool = filepath.Join(home, "bin", "keytool")
+	if runtime.GOOS == "windows" {
+		keytool += ".exe"
+	}
+	if !pathExists(keytool) {
+		keytool = ""
+	}
+	for _, path := range []string{
+		filepath.Join(home, "lib", "security", "cacerts"),
+		filepath.Join(home, "jre", "lib", "security", "cacerts"),
+	} {
+		if pathExists(path) {
+			return keytool, path
+		}
+	}
+	return keytool, ""
+}
+
+func (javaStore) available() (bool, string) {
+	keytool, cacerts := javaPaths()
+	switch {
+	case os.Getenv("JAVA_HOME") == "":
+		return false, "未设置 JAVA_HOME"
+	case keytool == "":
+		return false, "JAVA_HOME 中未找到 keytool"
+	case cacerts == "":
+		return false, "JAVA_HOME 中未找到 cacerts"
+	}
+	return true, cacerts
+}
+
+// keytool 执行 keytool，cacerts 不可写时使用 sudo
+func keytool(args ...string) ([]byte, error) {
+	path, cacerts := javaPaths()
+	args = append(args, "-keystore", cacerts, "-storepass", javaStorePass)
+	cmd := exec.Command(path, args...)
+	if file, err := os.OpenFile(cacerts, os.O_WRONLY, 0); err == nil {
+		_ = file.Close()
+	} else if runtime.GOOS != "windows" {
+		cmd = commandWithSudo(append([]string{path}, args...)...)
+	}
+	return cmd.CombinedOutput()
+}
+
+// installed 比较别名对应证书的 SHA-256 指纹
+func (javaStore) installed(cert *x509.Certificate) bool {
+	path, cacerts := javaPaths()
+	out, err := exec.Command(path, "-list", "-alias", trustNickname, "-keystore", cacerts, "-storepass", javaStorePass).CombinedOutput()
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256(cert.Raw)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+	compact := bytes.ToUpper(bytes.ReplaceAll(out, []byte(":"), nil))
+	return bytes.Contains(compact, []byte(fingerprint))
+}
+
+func (s javaStore) install(certPath string, cert *x509.Certificate) error {
+	// 别名已存在时 keytool 拒绝导入，先删除旧证书
+	_, _ = keytool("-delete", "-alias", trustNickname)
+	if out, err := keytool("-importcert", "-noprompt", "-alias", trustNickname, "-file", certPath); err != nil {
+		return fmt.Errorf("keytool 导入失败: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (s javaStore) uninstall(cert *x509.Certificate) error {
+	if !s.installed(cert) {
+		return nil
+	}
+	if out, err := keytool("-delete", "-alias", trustNickname); err != nil {
+		return fmt.Errorf("keytool 删除失败: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// nssStore Firefox 和 Linux 下 Chrome 使用的 NSS 证书数据库，通过 certutil 管理
+type nssStore struct{}
+
+func (nssStore) name() string { return StoreNSS }
+
+// certutilPath 查找 NSS 的 certutil，macOS 下通常由 Homebrew 的 nss 提供；Windows 自带的 certutil 是另一个工具
+func certutilPath() string {
+	if path, err := exec.LookPath("certutil"); err == nil && runtime.GOOS != "windows" {
+		return path
+	}
+	if runtime.GOOS == "darwin" {
+		for _, path := range []string{"/opt/homebrew/opt/nss/bin/certutil", "/usr/local/opt/nss/bin/certutil"} {
+			if pathExists(path) {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// nssDatabases 查找本机的 NSS 数据库，返回 certutil 使用的 sql:/dbm: 前缀路径
+func nssDatabases() []string {
+	home, _ := os.UserHomeDir()
+	var patterns []string
+	switch runtime.GOOS {
+	case "darwin":
+		patterns = []string{filepath.Join(home, "Library/Application Support/Firefox/Profiles/*")}
+	case "windows":
+		patterns = []string{filepath.Join(os.Getenv("APPDATA"), `Mozilla\Firefox\Profiles\*`)}
+	default:
+		patterns = []string{
+			filepath.Join(home, ".pki/nssdb"),
+			filepath.Join(home, "snap/chromium/current/.pki/nssdb"),
+			filepath.Join(home, ".mozilla/firefox/*"),
+			filepath.Join(home, "snap/firefox/common/.mozilla/firefox/*"),
+			filepath.Join(home, ".var/app/org.mozilla.firefox/.mozilla/firefox/*"),
+			"/etc/pki/nssdb",
+		}
+	}
+
+	var dbs []string
+	for _, pattern := range patterns {
+		dirs, _ := filepath.Glob(pattern)
+		for _, dir := range dirs {
+			if pathExists(filepath.Join(dir, "cert9.db")) {
+				dbs = append(dbs, "sql:"+dir)
+			} else if pathExists(filepath.Join(dir, "cert8.db")) {
+				dbs = append(dbs, "dbm:"+dir)
+			}
+		}
+	}
+	return dbs
+}
+
+func (nssStore) available() (bool, string) {
+	dbs := nssDatabases()
+	if len(dbs) == 0 {
+		return false, "未找到 Firefox/Chrome 的 NSS 数据库"
+	}
+	if certutilPath() == "" {
+		return false, fmt.Sprintf("找到 %d 个 NSS 数据库，但未安装 certutil（libnss3-tools / nss-tools / brew install nss）", len(dbs))
+	}
+	return true, strings.Join(dbs, ", ")
+}
+
+// installed 所有数据库中都存在该证书时视为已安装
+func (nssStore) installed(cert *x509.Certificate) bool {
+	dbs := nssDatabases()
+	if len(dbs) == 0 {
+		return false
+	}
+	for _, db := range dbs {
+		out, err := exec.Command(certutilPath(), "-L", "-d", db, "-n", trustNickname, "-a").Output()
+		if err != nil || !containsCert(out, cert) {
+			return false
+		}
+	}
+	return true
+}
+
+func (nssStore) install(certPath string, _ *x509.Certificate) error {
+	var errs []error
+	for _, db := range nssDatabases() {
+		// 先删除同名的旧证书，证书更换后别名不变
+		_ = exec.Command(certutilPath(), "-D", "-d", db, "-n", trustNickname).Run()
+		out, err := exec.Command(certutilPath(), "-A", "-d", db, "-t", "C,,", "-n", trustNickname, "-i", certPath).CombinedOutput()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", db, strings.TrimSpace(string(out))))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (nssStore) uninstall(*x509.Certificate) error {
+	var errs []error
+	for _, db := range nssDatabases() {
+		// 证书不存在时 certutil 也会返回错误，先检查
+		if exec.Command(certutilPath(), "-L", "-d", db, "-n", trustNickname).Run() != nil {
+			continue
+		}
+		if out, err := exec.Command(certutilPath(), "-D", "-d", db, "-n", trustNickname).CombinedOutput(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", db, strings.TrimSpace(string(out))))
+		}
+	}
+	return errors.Join(errs...)
+}
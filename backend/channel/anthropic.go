@@ -9,6 +9,7 @@ import (
 
 	"github.com/gookit/goutil/errorx"
 	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/transport"
 	"github.com/tidwall/gjson"
 )
 
@@ -28,7 +29,7 @@ func fetchAnthropicModels(node *Channel) error {
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("anthropic-version", "2023-06-01")
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := transport.Client(node.Outbound()).Do(request)
 	if err != nil {
 		return err
 	}
@@ -74,7 +75,7 @@ func testAnthropicChannel(node *Channel) (string, error) {
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("anthropic-version", "2023-06-01")
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := transport.Client(node.Outbound()).Do(request)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,99 @@
+package channel
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EventKind 渠道配置变更类型
+type EventKind string
+
+const (
+	EventGroupAdded     EventKind = "group_added"
+	EventGroupUpdated   EventKind = "group_updated"
+	EventGroupDeleted   EventKind = "group_deleted"
+	EventChannelAdded   EventKind = "channel_added"
+	EventChannelUpdated EventKind = "channel_updated"
+	EventChannelDeleted EventKind = "channel_deleted"
+	EventReloaded       EventKind = "reloaded" // channels.json 被外部修改后重新加载
+)
+
+// Event 渠道配置变更事件
+type Event struct {
+	Kind    EventKind
+	Group   string // 渠道组端点
+	Channel string // 渠道名称，渠道组事件为空
+}
+
+// Subscribe 订阅渠道配置变更，返回事件通道和取消订阅函数。
+// 订阅者处理不及时时新事件会被丢弃，事件只作为变更通知，订阅者应重新读取完整配置
+func (m *Manager) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+	m.subMu.Lock()
+	m.subscribers[ch] = struct{}{}
+	m.subMu.Unlock()
+
+	return ch, func() {
+		m.subMu.Lock()
+		defer m.subMu.Unlock()
+		if _, ok := m.subscribers[ch]; ok {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish 通知所有订阅者，不阻塞调用方
+func (m *Manager) publish(event Event) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	for ch := range m.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Watch 定时检查 channels.json，文件被外部修改时重新加载，返回停止函数
+func (m *Manager) Watch(interval time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.checkFile()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// checkFile 比较文件修改时间和大小，与最近一次加载或保存时不同则重新加载
+func (m *Manager) checkFile() {
+	info, err := os.Stat(filepath.Join(m.dataPath, "channels.json"))
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	stamp := m.fileStamp
+	// 先记录本次检查到的状态，内容有误时只报告一次，等待下一次修改
+	m.fileStamp = info
+	m.mu.Unlock()
+	if stamp != nil && info.ModTime().Equal(stamp.ModTime()) && info.Size() == stamp.Size() {
+		return
+	}
+
+	slog.Info("检测到渠道配置文件变更，重新加载")
+	if err := m.LoadFromFile(); err != nil {
+		slog.Error("重新加载渠道配置失败", "error", err)
+		return
+	}
+	m.publish(Event{Kind: EventReloaded})
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gookit/goutil/errorx"
 	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/transport"
 	"github.com/tidwall/gjson"
 )
 
@@ -25,7 +26,7 @@ func fetchGeminiModels(node *Channel) error {
 	}
 	request.Header.Set("x-goog-api-key", node.ApiKey)
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := transport.Client(node.Outbound()).Do(request)
 	if err != nil {
 		return err
 	}
@@ -69,7 +70,7 @@ func testGeminiChannel(node *Channel) (string, error) {
 	request.Header.Set("x-goog-api-key", node.ApiKey)
 	request.Header.Set("Content-Type", "application/json")
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := transport.Client(node.Outbound()).Do(request)
 	if err != nil {
 		return "", err
 	}
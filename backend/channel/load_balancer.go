@@ -27,7 +27,7 @@ func CreateLoadBalancer(strategy LBStrategy) (LoadBalancer, error) {
 	case LB_RANDOM:
 		return &RandomBalancer{}, nil
 	default:
-		return nil, errorx.Ef("不支持的负载均衡策略: %d", strategy)
+		return nil, errorx.Ef("不支持的负载均衡策略: %d，可选 1(优先级)|2(轮询)|3(加权轮询)|4(随机)", strategy)
 	}
 }
 
@@ -8,22 +8,27 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
 
 	"github.com/gookit/goutil/errorx"
 )
 
 type Manager struct {
-	dataPath string
-	groups   map[string]*Group
-	mu       sync.RWMutex
+	dataPath    string
+	groups      map[string]*Group
+	fileStamp   os.FileInfo // 最近一次加载或保存时 channels.json 的状态，用于发现外部修改
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+	subMu       sync.Mutex
 }
 
 // NewManager 创建渠道管理器
 func NewManager(dataPath string) *Manager {
 	return &Manager{
-		dataPath: dataPath,
-		groups:   make(map[string]*Group),
+		dataPath:    dataPath,
+		groups:      make(map[string]*Group),
+		subscribers: make(map[chan Event]struct{}),
 	}
 }
 
@@ -48,16 +53,20 @@ func (m *Manager) LoadFromFile() error {
 		return nil
 	}
 
-	// 解析JSON配置
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if err := json.Unmarshal(data, &m.groups); err != nil {
+	// 解析JSON配置，解析并校验成功后整体替换，重新加载时删除的渠道组不会残留
+	groups, version, err := decodeFile(data)
+	if err != nil {
 		return fmt.Errorf("解析渠道配置文件失败: %w", err)
 	}
+	if err := validateGroups(groups); err != nil {
+		return fmt.Errorf("渠道配置校验失败: %w", err)
+	}
 
-	for _, group := range m.groups {
-		if group.LoadBalancer, err = CreateLoadBalancer(group.LBStrategy); err != nil {
-			slog.Error("负载均衡器创建失败", "group", group.Endpoint, "strategy", group.LBStrategy)
+	for _, group := range groups {
+		group.LoadBalancer, _ = CreateLoadBalancer(group.LBStrategy)
+		// 空渠道组保存时会省略 channels 字段
+		if group.Channels == nil {
+			group.Channels = make(map[string]*Channel)
 		}
 		for _, channel := range group.Channels {
 			channel.ConverterName = fmt.Sprintf("%s->%s", group.Provider, channel.Provider)
@@ -67,7 +76,22 @@ func (m *Manager) LoadFromFile() error {
 			}
 		}
 	}
-	slog.Info("成功加载渠道配置", "groups", len(m.groups), "path", configPath)
+
+	m.mu.Lock()
+	m.groups = groups
+	m.stamp(configPath)
+	m.mu.Unlock()
+	slog.Info("成功加载渠道配置", "groups", len(groups), "path", configPath)
+
+	// 旧版本文件备份后按当前版本重新保存
+	if version < SchemaVersion {
+		backup := fmt.Sprintf("%s.v%d.bak", configPath, version)
+		if err := os.WriteFile(backup, data, 0644); err != nil {
+			return fmt.Errorf("备份旧版本渠道配置失败: %w", err)
+		}
+		slog.Info("渠道配置文件已升级", "from", version, "to", SchemaVersion, "backup", backup)
+		return m.SaveToFile()
+	}
 	return nil
 }
 
@@ -144,6 +168,7 @@ func (m *Manager) AddGroup(group *Group) error {
 
 	m.groups[group.Endpoint] = group
 	slog.Info("添加渠道组", "endpoint", group.Endpoint, "strategy", group.LBStrategy)
+	m.publish(Event{Kind: EventGroupAdded, Group: group.Endpoint})
 
 	return nil
 }
@@ -184,6 +209,7 @@ func (m *Manager) UpdateGroup(group *Group) error {
 
 	m.groups[group.Endpoint] = group
 	slog.Info("更新渠道组", "endpoint", group.Endpoint)
+	m.publish(Event{Kind: EventGroupUpdated, Group: group.Endpoint})
 
 	return nil
 }
@@ -199,6 +225,7 @@ func (m *Manager) DeleteGroup(endpoint string) error {
 
 	delete(m.groups, endpoint)
 	slog.Info("删除渠道组", "endpoint", endpoint)
+	m.publish(Event{Kind: EventGroupDeleted, Group: endpoint})
 
 	return nil
 }
@@ -239,6 +266,7 @@ func (m *Manager) AddChannel(groupEndpoint string, channel *Channel) error {
 
 	group.Channels[channel.Name] = channel
 	slog.Info("添加渠道", "group", groupEndpoint, "channel", channel.Name, "endpoint", channel.URL)
+	m.publish(Event{Kind: EventChannelAdded, Group: groupEndpoint, Channel: channel.Name})
 
 	return nil
 }
@@ -265,6 +293,7 @@ func (m *Manager) UpdateChannel(groupEndpoint string, channel *Channel) error {
 
 	group.Channels[channel.Name] = channel
 	slog.Info("更新渠道", "group", groupEndpoint, "channel", channel.Name)
+	m.publish(Event{Kind: EventChannelUpdated, Group: groupEndpoint, Channel: channel.Name})
 
 	return nil
 }
@@ -285,6 +314,7 @@ func (m *Manager) DeleteChannel(groupEndpoint, channelName string) error {
 
 	delete(group.Channels, channelName)
 	slog.Info("删除渠道", "group", groupEndpoint, "channel", channelName)
+	m.publish(Event{Kind: EventChannelDeleted, Group: groupEndpoint, Channel: channelName})
 
 	return nil
 }
@@ -334,11 +364,11 @@ func (m *Manager) SetChannelStatus(groupEndpoint, channelName string, status Sta
 func (m *Manager) SaveToFile() error {
 	path := filepath.Join(m.dataPath, "channels.json")
 
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	// 序列化为JSON
-	data, err := json.MarshalIndent(m.groups, "", "  ")
+	data, err := json.MarshalIndent(channelsFile{SchemaVersion: SchemaVersion, Groups: m.groups}, "", "  ")
 	if err != nil {
 		return fmt.Errorf("序列化渠道配置失败: %w", err)
 	}
@@ -352,11 +382,44 @@ func (m *Manager) SaveToFile() error {
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("写入渠道配置文件失败: %w", err)
 	}
+	m.stamp(path)
 
 	slog.Info("成功保存渠道配置", "groups", len(m.groups), "path", path)
 	return nil
 }
 
+// stamp 记录 channels.json 当前状态，自身的读写不触发重新加载，调用方需持有写锁
+func (m *Manager) stamp(path string) {
+	if info, err := os.Stat(path); err == nil {
+		m.fileStamp = info
+	}
+}
+
+// MatchGroup 根据请求域名查找渠道组，精确匹配优先，其次为最长的通配符端点（如 *.openai.azure.com）
+func (m *Manager) MatchGroup(host string) (*Group, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if group, ok := m.groups[host]; ok {
+		return group, true
+	}
+	var matched *Group
+	for endpoint, group := range m.groups {
+		if MatchHost(endpoint, host) && (matched == nil || len(endpoint) > len(matched.Endpoint)) {
+			matched = group
+		}
+	}
+	return matched, matched != nil
+}
+
+// MatchHost 判断域名是否匹配端点，*.example.com 匹配 example.com 的任意子域名
+func MatchHost(endpoint, host string) bool {
+	if suffix, ok := strings.CutPrefix(endpoint, "*"); ok && strings.HasPrefix(suffix, ".") {
+		return len(host) > len(suffix) && strings.HasSuffix(strings.ToLower(host), strings.ToLower(suffix))
+	}
+	return strings.EqualFold(endpoint, host)
+}
+
 func (m *Manager) SelectChannel(endpoint string) (*Channel, error) {
 	group, err := m.GetGroup(endpoint)
 	if err != nil {
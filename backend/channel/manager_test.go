@@ -0,0 +1,108 @@
+package channel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 命令行中没有创建统计管理器，测试渠道不应 panic
+func TestTestChannelWithoutStatistics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	mgr := NewManager(t.TempDir())
+	if err := mgr.AddGroup(&Group{Endpoint: "api.openai.com", Enabled: true, LBStrategy: LB_PRIORITY, Provider: "openai"}); err != nil {
+		t.Fatal(err)
+	}
+	ch := &Channel{Name: "test", Enabled: true, URL: upstream.URL, Provider: "openai", TestModel: "gpt-4o"}
+	if err := mgr.AddChannel("api.openai.com", ch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.TestChannel("api.openai.com", "test"); err != nil {
+		t.Fatalf("TestChannel() error = %v", err)
+	}
+	if ch.Status != STATUS_NORMAL {
+		t.Errorf("Status = %d, want %d", ch.Status, STATUS_NORMAL)
+	}
+}
+
+func TestUpdateChannelPublishesEvent(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	if err := mgr.AddGroup(&Group{Endpoint: "api.openai.com", LBStrategy: LB_PRIORITY, Provider: "openai"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.AddChannel("api.openai.com", &Channel{Name: "test", Enabled: true, URL: "https://api.openai.com", Provider: "openai"}); err != nil {
+		t.Fatal(err)
+	}
+	events, cancel := mgr.Subscribe()
+	defer cancel()
+
+	ch, _ := mgr.GetChannel("api.openai.com", "test")
+	updated := *ch
+	updated.Enabled = false
+	if err := mgr.UpdateChannel("api.openai.com", &updated); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if event.Kind != EventChannelUpdated || event.Channel != "test" {
+			t.Errorf("event = %+v", event)
+		}
+	default:
+		t.Fatal("no event published")
+	}
+	if ch, _ := mgr.GetChannel("api.openai.com", "test"); ch.Enabled {
+		t.Error("channel still enabled")
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	tests := []struct {
+		endpoint, host string
+		want           bool
+	}{
+		{"api.openai.com", "api.openai.com", true},
+		{"api.openai.com", "API.OpenAI.com", true},
+		{"api.openai.com", "x.api.openai.com", false},
+		{"*.openai.azure.com", "east.openai.azure.com", true},
+		{"*.openai.azure.com", "a.b.openai.azure.com", true},
+		{"*.openai.azure.com", "EAST.OPENAI.AZURE.COM", true},
+		{"*.openai.azure.com", "openai.azure.com", false},
+		{"*.openai.azure.com", ".openai.azure.com", false},
+		{"*.openai.azure.com", "evilopenai.azure.com", false},
+		{"*openai.azure.com", "xopenai.azure.com", false},
+		{"*", "anything", false},
+	}
+	for _, tt := range tests {
+		if got := MatchHost(tt.endpoint, tt.host); got != tt.want {
+			t.Errorf("MatchHost(%q, %q) = %v, want %v", tt.endpoint, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestMatchGroupPrefersExactThenLongest(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	for _, endpoint := range []string{"*.azure.com", "*.openai.azure.com", "east.openai.azure.com"} {
+		if err := mgr.AddGroup(&Group{Endpoint: endpoint, LBStrategy: LB_PRIORITY, Provider: "openai"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := map[string]string{
+		"east.openai.azure.com": "east.openai.azure.com",
+		"west.openai.azure.com": "*.openai.azure.com",
+		"blob.azure.com":        "*.azure.com",
+	}
+	for host, want := range tests {
+		group, ok := mgr.MatchGroup(host)
+		if !ok || group.Endpoint != want {
+			t.Errorf("MatchGroup(%q) = %v, want %q", host, group, want)
+		}
+	}
+	if _, ok := mgr.MatchGroup("azure.com"); ok {
+		t.Error("MatchGroup(azure.com) matched a wildcard")
+	}
+}
@@ -0,0 +1,101 @@
+package channel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// SchemaVersion 当前 channels.json 的结构版本，修改文件结构时递增，并在 migrations 中注册迁移
+const SchemaVersion = 1
+
+// channelsFile channels.json 的文件结构
+type channelsFile struct {
+	SchemaVersion int               `json:"schema_version"`
+	Groups        map[string]*Group `json:"groups"`
+}
+
+// migrations 渠道配置迁移，migrations[i] 将版本 i 的文件升级到版本 i+1，在解析为渠道组之前对原始 JSON 进行修改
+var migrations = []func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error){
+	// 0 -> 1：旧版本文件顶层即为渠道组，移动到 groups 字段下
+	func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		groups, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]json.RawMessage{"groups": groups}, nil
+	},
+}
+
+// decodeFile 解析 channels.json，旧版本先迁移到当前版本，返回渠道组和文件原始版本
+func decodeFile(data []byte) (map[string]*Group, int, error) {
+	doc := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+
+	version := 0
+	if raw, ok := doc["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil || version < 0 {
+			return nil, 0, fmt.Errorf("schema_version: 不合法的版本 %s", raw)
+		}
+	}
+	if version > SchemaVersion {
+		return nil, version, fmt.Errorf("渠道配置版本 %d 高于当前程序支持的版本 %d，请升级程序", version, SchemaVersion)
+	}
+	for v := version; v < SchemaVersion; v++ {
+		var err error
+		if doc, err = migrations[v](doc); err != nil {
+			return nil, version, fmt.Errorf("渠道配置从版本 %d 迁移到 %d 失败: %w", v, v+1, err)
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, version, err
+	}
+	var file channelsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, version, err
+	}
+	if file.Groups == nil {
+		file.Groups = make(map[string]*Group)
+	}
+	return file.Groups, version, nil
+}
+
+// validateGroups 校验渠道组并补全缺失的字段，返回所有不合法的渠道组
+func validateGroups(groups map[string]*Group) error {
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(groups)) {
+		group := groups[key]
+		if group == nil {
+			errs = append(errs, fmt.Errorf("渠道组 %s: 配置为空", key))
+			continue
+		}
+		if group.Endpoint == "" {
+			group.Endpoint = key
+		} else if group.Endpoint != key {
+			errs = append(errs, fmt.Errorf("渠道组 %s: endpoint %q 与键不一致", key, group.Endpoint))
+		}
+		// 未设置负载均衡策略时使用优先级策略
+		if group.LBStrategy == 0 {
+			group.LBStrategy = LB_PRIORITY
+		}
+		if _, err := CreateLoadBalancer(group.LBStrategy); err != nil {
+			errs = append(errs, fmt.Errorf("渠道组 %s: lb_strategy: %w", key, err))
+		}
+		for name, channel := range group.Channels {
+			if channel == nil {
+				errs = append(errs, fmt.Errorf("渠道组 %s 渠道 %s: 配置为空", key, name))
+				continue
+			}
+			if channel.Name == "" {
+				channel.Name = name
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,117 @@
+package channel
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantVersion int
+		wantGroups  []string
+		wantErr     string
+	}{
+		{
+			name:        "旧版本顶层即为渠道组",
+			data:        `{"api.openai.com":{"provider":"openai","channels":{"a":{"provider":"openai"}}}}`,
+			wantVersion: 0,
+			wantGroups:  []string{"api.openai.com"},
+		},
+		{
+			name:        "当前版本",
+			data:        `{"schema_version":1,"groups":{"api.anthropic.com":{"provider":"anthropic"}}}`,
+			wantVersion: 1,
+			wantGroups:  []string{"api.anthropic.com"},
+		},
+		{name: "空文件结构", data: `{"schema_version":1}`, wantVersion: 1},
+		{name: "版本高于当前程序", data: `{"schema_version":99,"groups":{}}`, wantErr: "高于当前程序支持的版本"},
+		{name: "版本不合法", data: `{"schema_version":"1"}`, wantErr: "schema_version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, version, err := decodeFile([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodeFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeFile() error = %v", err)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", version, tt.wantVersion)
+			}
+			if len(groups) != len(tt.wantGroups) {
+				t.Fatalf("groups = %v, want %v", groups, tt.wantGroups)
+			}
+			for _, key := range tt.wantGroups {
+				if groups[key] == nil {
+					t.Errorf("缺少渠道组 %s", key)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	groups := map[string]*Group{
+		"api.openai.com": {Provider: "openai", Channels: map[string]*Channel{"a": {Provider: "openai"}}},
+	}
+	if err := validateGroups(groups); err != nil {
+		t.Fatalf("validateGroups() error = %v", err)
+	}
+	group := groups["api.openai.com"]
+	if group.Endpoint != "api.openai.com" || group.LBStrategy != LB_PRIORITY || group.Channels["a"].Name != "a" {
+		t.Errorf("未补全缺失字段: %+v", group)
+	}
+
+	invalid := map[string]*Group{
+		"a.com": nil,
+		"b.com": {Endpoint: "c.com", Provider: "openai"},
+		"d.com": {Provider: "openai", LBStrategy: 99},
+		"e.com": {Provider: "openai", Channels: map[string]*Channel{"x": nil}},
+	}
+	err := validateGroups(invalid)
+	if err == nil {
+		t.Fatal("validateGroups() error = nil, want error")
+	}
+	for _, want := range []string{"a.com", "b.com", "d.com", "e.com"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("错误 %q 中缺少渠道组 %s", err, want)
+		}
+	}
+}
+
+// 旧版本文件加载后备份原文件并按当前版本重新保存
+func TestLoadFromFileUpgradesLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "channels.json")
+	legacy := `{"api.openai.com":{"enabled":true,"provider":"openai","channels":{"a":{"enabled":true,"provider":"openai"}}}}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := NewManager(dir)
+	if err := mgr.LoadFromFile(); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	if _, err := mgr.GetChannel("api.openai.com", "a"); err != nil {
+		t.Fatalf("GetChannel() error = %v", err)
+	}
+
+	if backup, _ := os.ReadFile(path + ".v0.bak"); string(backup) != legacy {
+		t.Errorf("备份内容 = %q, want %q", backup, legacy)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, version, err := decodeFile(data); err != nil || version != SchemaVersion {
+		t.Errorf("升级后 version = %d, error = %v, want %d", version, err, SchemaVersion)
+	}
+}
@@ -92,7 +92,7 @@ func (m *ModelMapper) matchRule(rule ModelMappingRule, model string) bool {
 	case ExactMatch:
 		return model == rule.Pattern
 	case WildcardMatch:
-		return m.wildcardMatch(rule.Pattern, model)
+		return MatchModel(rule.Pattern, model)
 	case AllMatch:
 		return true
 	default:
@@ -100,8 +100,8 @@ func (m *ModelMapper) matchRule(rule ModelMappingRule, model string) bool {
 	}
 }
 
-// wildcardMatch 通配符匹配
-func (m *ModelMapper) wildcardMatch(pattern, model string) bool {
+// MatchModel 通配符匹配模型名称
+func MatchModel(pattern, model string) bool {
 	// 简单的通配符实现，支持 * 在开头、结尾或中间
 	if !strings.Contains(pattern, "*") {
 		return pattern == model
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gookit/goutil/errorx"
 	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/transport"
 	"github.com/tidwall/gjson"
 )
 
@@ -26,7 +27,7 @@ func fetchOpenaiModels(node *Channel) error {
 	request.Header.Set("Authorization", "Bearer "+node.ApiKey)
 	request.Header.Set("Content-Type", "application/json")
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := transport.Client(node.Outbound()).Do(request)
 	if err != nil {
 		return err
 	}
@@ -70,7 +71,7 @@ func testOpenaiChannel(node *Channel) (string, error) {
 	request.Header.Set("Authorization", "Bearer "+node.ApiKey)
 	request.Header.Set("Content-Type", "application/json")
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := transport.Client(node.Outbound()).Do(request)
 	if err != nil {
 		return "", err
 	}
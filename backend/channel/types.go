@@ -2,6 +2,9 @@ package channel
 
 import (
 	"fmt"
+	"slices"
+
+	"github.com/sbgayhub/chameleon/backend/transport"
 )
 
 type LBStrategy uint8
@@ -28,6 +31,11 @@ type Channel struct {
 	ModelMapping  map[string]string `json:"model_mapping,omitempty"` // 模型映射
 	Status        Status            `json:"status,omitempty"`        // 状态
 	TestModel     string            `json:"test_model"`              // 用于测试的模型
+	InputPrice    float64           `json:"input_price,omitempty"`   // 输入单价（每百万token）
+	OutputPrice   float64           `json:"output_price,omitempty"`  // 输出单价（每百万token）
+	Proxy         string            `json:"proxy,omitempty"`         // 上游代理，为空时使用全局配置，direct 表示直连
+	CACert        string            `json:"ca_cert,omitempty"`       // 额外信任的 CA 证书文件（PEM），用于自建服务
+	Insecure      bool              `json:"insecure,omitempty"`      // 跳过上游证书校验
 	ConverterName string            `json:"-"`                       // 使用的转换器名称
 	ModelMapper   *ModelMapper      `json:"-"`                       // 模型映射器（运行时使用）
 	Models        []string          `json:"-"`                       // 渠道的模型列表
@@ -82,3 +90,29 @@ func (c *Channel) init() error {
 	}
 	return nil
 }
+
+// KnownModel 判断模型是否为渠道已知的模型：模型列表、映射规则的目标模型或测试模型
+func (c *Channel) KnownModel(model string) bool {
+	if model == "" {
+		return false
+	}
+	if model == c.TestModel || slices.Contains(c.Models, model) {
+		return true
+	}
+	if c.ModelMapper == nil {
+		for _, target := range c.ModelMapping {
+			if target == model {
+				return true
+			}
+		}
+		return false
+	}
+	return slices.ContainsFunc(c.ModelMapper.GetRules(), func(rule ModelMappingRule) bool {
+		return rule.Target == model
+	})
+}
+
+// Outbound 获取渠道的出站设置
+func (c *Channel) Outbound() transport.Options {
+	return transport.Options{Channel: c.Name, Proxy: c.Proxy, CACert: c.CACert, Insecure: c.Insecure}
+}
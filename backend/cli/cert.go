@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sbgayhub/chameleon/backend/certificate"
+)
+
+func init() {
+	register(&command{name: "cert", usage: "管理 CA 证书（status|install|uninstall|export|import|rotate）", run: func(args []string) error {
+		return dispatch("cert", map[string]subcommand{
+			"status":    {usage: "查看 CA 证书在各信任存储中的安装状态", run: certStatus},
+			"install":   {usage: "将 CA 证书安装到系统或指定的信任存储", run: certInstall},
+			"uninstall": {usage: "从系统或指定的信任存储中移除 CA 证书", run: certUninstall},
+			"export":    {usage: "导出 CA 证书", run: certExport},
+			"import":    {usage: "导入自有 CA 证书和私钥（PEM 或 PKCS#12）", run: certImport},
+			"rotate":    {usage: "生成新的 CA 证书并替换系统信任存储中的旧证书", run: certRotate},
+		}, args)
+	}})
+}
+
+// certStatus chameleon cert status [--json]
+func certStatus(args []string) error {
+	fs, dataDir := newFlagSet("cert status")
+	asJSON := fs.Bool("json", false, "以 JSON 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	status := certificate.NewManager(dataDir()).Status()
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(status)
+	}
+	if status.Subject == "" {
+		return fmt.Errorf("%s", status.Error)
+	}
+
+	validity := "有效"
+	switch {
+	case status.Expired:
+		validity = "已过期"
+	case status.ExpiringSoon:
+		validity = "即将过期"
+	}
+	fmt.Printf("CA:          %s\n", status.Subject)
+	fmt.Printf("Serial:      %s\n", status.Serial)
+	fmt.Printf("SHA-256:     %s\n", status.Fingerprint)
+	fmt.Printf("Expires:     %s (%s)\n", status.NotAfter.Local().Format("2006-01-02 15:04:05"), validity)
+	fmt.Printf("System:      %t\n\n", status.Installed)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STORE\tAVAILABLE\tINSTALLED\tDETAIL")
+	for _, store := range status.Stores {
+		fmt.Fprintf(w, "%s\t%t\t%t\t%s\n", store.Name, store.Available, store.Installed, store.Detail)
+	}
+	return w.Flush()
+}
+
+// certInstall chameleon cert install [--store nss,java,env]
+func certInstall(args []string) error {
+	fs, dataDir := newFlagSet("cert install")
+	var stores listFlag
+	fs.Var(&stores, "store", "信任存储 system|nss|java|env，可用逗号分隔多个，默认为 system")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dir := dataDir()
+	if len(stores) > 0 {
+		if err := certificate.NewManager(dir).InstallStores(stores...); err != nil {
+			return err
+		}
+		fmt.Printf("证书已安装到 %s\n", stores.String())
+		return nil
+	}
+	if !certificate.NewManager(dir).Install() {
+		return fmt.Errorf("安装证书失败，可能需要管理员权限")
+	}
+	fmt.Println("证书已安装")
+	return nil
+}
+
+// certUninstall chameleon cert uninstall [--store nss,java,env] [--legacy]
+func certUninstall(args []string) error {
+	fs, dataDir := newFlagSet("cert uninstall")
+	legacy := fs.Bool("legacy", false, "移除旧版本内置的公共 CA 证书")
+	var stores listFlag
+	fs.Var(&stores, "store", "信任存储 system|nss|java|env，可用逗号分隔多个，默认为 system")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	mgr := certificate.NewManager(dataDir())
+	if len(stores) > 0 {
+		if err := mgr.UninstallStores(stores...); err != nil {
+			return err
+		}
+		fmt.Printf("已从 %s 移除证书\n", stores.String())
+		return nil
+	}
+	if *legacy {
+		if !mgr.UninstallLegacy() {
+			return fmt.Errorf("卸载旧版本证书失败，可能未安装或需要管理员权限")
+		}
+		fmt.Println("旧版本证书已卸载")
+		return nil
+	}
+	if !mgr.Uninstall() {
+		return fmt.Errorf("卸载证书失败，可能需要管理员权限")
+	}
+	fmt.Println("证书已卸载")
+	return nil
+}
+
+// certExport chameleon cert export [--out ca.pem] [--format pem|der]
+func certExport(args []string) error {
+	fs, dataDir := newFlagSet("cert export")
+	out := fs.String("out", "", "输出文件路径，为空时输出到标准输出")
+	format := fs.String("format", certificate.FormatPEM, "导出格式 pem|der")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	data, err := certificate.NewManager(dataDir()).ExportCertAs(*format)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		return fmt.Errorf("写入证书失败: %w", err)
+	}
+	fmt.Printf("证书已导出到 %s\n", *out)
+	return nil
+}
+
+// certImport chameleon cert import <证书文件> [--key 私钥文件] [--password 密码]
+func certImport(args []string) error {
+	fs, dataDir := newFlagSet("cert import")
+	key := fs.String("key", "", "PEM 私钥文件，证书文件中已包含私钥或为 PKCS#12 时可省略")
+	password := fs.String("password", "", "PKCS#12 文件密码")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := positional(rest, "<证书文件>"); err != nil {
+		return err
+	}
+	if err := certificate.NewManager(dataDir()).ImportFile(rest[0], *key, *password); err != nil {
+		return err
+	}
+	fmt.Println("CA 证书已导入，重启代理服务后生效")
+	return nil
+}
+
+// certRotate chameleon cert rotate [--algorithm ecdsa|rsa]
+func certRotate(args []string) error {
+	fs, dataDir := newFlagSet("cert rotate")
+	algorithm := fs.String("algorithm", "", "密钥算法 ecdsa|rsa，为空时与当前证书相同")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := certificate.NewManager(dataDir()).Regenerate(*algorithm); err != nil {
+		return err
+	}
+	fmt.Println("已生成并安装新的 CA 证书")
+	return nil
+}
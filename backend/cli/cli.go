@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sbgayhub/chameleon/backend/service"
+)
+
+// command 命令行子命令
+type command struct {
+	name  string                    // 命令名称
+	usage string                    // 命令说明
+	run   func(args []string) error // 执行函数，参数不包含命令名称
+}
+
+var commands = map[string]*command{}
+
+// GUI 程序是否包含图形界面，无界面程序（cmd/chameleon-server）中为 false
+var GUI = true
+
+// register 注册子命令
+func register(c *command) {
+	commands[c.name] = c
+}
+
+// IsCommand 判断命令行参数是否为子命令调用，否则以图形界面模式启动
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		return true
+	}
+	_, ok := commands[args[0]]
+	return ok
+}
+
+// Run 执行子命令，返回进程退出码
+func Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	// 管理命令只输出警告及以上级别的日志，serve 会按配置重新初始化日志
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	c, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", args[0])
+		printUsage(os.Stderr)
+		return 2
+	}
+	if err := c.run(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printUsage 输出命令列表
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "用法: %s <命令> [参数]\n", filepath.Base(os.Args[0]))
+	if GUI {
+		fmt.Fprintln(w, "不带命令时以图形界面模式启动。")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "命令:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].usage)
+	}
+}
+
+// newFlagSet 创建带有 --data-dir 参数的参数解析器，返回的函数需在参数解析后调用获取数据目录。
+// 默认目录在使用时才解析，避免指定 --data-dir 时仍在程序目录下创建 data
+func newFlagSet(name string) (*flag.FlagSet, func() string) {
+	fs := flag.NewFlagSet("chameleon "+name, flag.ContinueOnError)
+	dataDir := fs.String("data-dir", "", "数据目录，包含 config.toml 和 channels.json，默认为程序目录下的 data")
+	return fs, func() string {
+		if *dataDir == "" {
+			return service.DefaultDataDir()
+		}
+		return *dataDir
+	}
+}
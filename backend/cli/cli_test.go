@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataDirFlag(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	defaultDir := filepath.Join(filepath.Dir(exe), "data")
+	if _, err := os.Stat(defaultDir); err == nil {
+		t.Skipf("%s already exists", defaultDir)
+	}
+
+	// 指定 --data-dir 时不应创建程序目录下的默认数据目录
+	dir := t.TempDir()
+	fs, dataDir := newFlagSet("test")
+	if err := fs.Parse([]string{"--data-dir", dir}); err != nil {
+		t.Fatal(err)
+	}
+	if got := dataDir(); got != dir {
+		t.Fatalf("dataDir() = %q, want %q", got, dir)
+	}
+	if _, err := os.Stat(defaultDir); !os.IsNotExist(err) {
+		t.Fatalf("default data dir created: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+)
+
+func init() {
+	register(&command{name: "group", usage: "管理渠道组（add|list|rm）", run: func(args []string) error {
+		return dispatch("group", map[string]subcommand{
+			"add":  {usage: "添加渠道组", run: groupAdd},
+			"list": {usage: "列出渠道组及渠道", run: groupList},
+			"rm":   {usage: "删除渠道组", run: groupRemove},
+		}, args)
+	}})
+}
+
+// groupAdd chameleon group add <endpoint> --provider anthropic [--lb priority]
+func groupAdd(args []string) error {
+	fs, dataDir := newFlagSet("group add <endpoint>")
+	provider := fs.String("provider", "anthropic", "客户端请求格式：anthropic|openai|gemini")
+	lb := fs.String("lb", "priority", "负载均衡策略：priority|round|weighted|random")
+	priority := fs.Uint("priority", 0, "优先级（用于界面排序）")
+	disabled := fs.Bool("disabled", false, "添加后不启用")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := positional(rest, "<endpoint>"); err != nil {
+		return err
+	}
+	if err := validProvider(*provider); err != nil {
+		return err
+	}
+	strategy, ok := lbStrategies[*lb]
+	if !ok {
+		return fmt.Errorf("不支持的负载均衡策略: %s", *lb)
+	}
+
+	mgr, err := loadChannels(dataDir())
+	if err != nil {
+		return err
+	}
+	group := &channel.Group{
+		Endpoint:   rest[0],
+		Enabled:    !*disabled,
+		Priority:   uint8(*priority),
+		LBStrategy: strategy,
+		Provider:   *provider,
+	}
+	if err := mgr.AddGroup(group); err != nil {
+		return err
+	}
+	if err := mgr.SaveToFile(); err != nil {
+		return err
+	}
+	fmt.Printf("已添加渠道组 %s\n", group.Endpoint)
+	return nil
+}
+
+// groupList chameleon group list
+func groupList(args []string) error {
+	fs, dataDir := newFlagSet("group list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	mgr, err := loadChannels(dataDir())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tPROVIDER\tLB\tENABLED\tCHANNEL\tCHANNEL PROVIDER\tPRIORITY\tCHANNEL ENABLED\tSTATUS")
+	for _, group := range mgr.List() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t\t\t\t\t\n", group.Endpoint, group.Provider, lbName(group.LBStrategy), group.Enabled)
+		channels := make([]*channel.Channel, 0, len(group.Channels))
+		for _, ch := range group.Channels {
+			channels = append(channels, ch)
+		}
+		slices.SortFunc(channels, func(a, b *channel.Channel) int {
+			return int(a.Priority) - int(b.Priority)
+		})
+		for _, ch := range channels {
+			fmt.Fprintf(w, "\t\t\t\t%s\t%s\t%d\t%t\t%s\n", ch.Name, ch.Provider, ch.Priority, ch.Enabled, statusName(ch.Status))
+		}
+	}
+	return w.Flush()
+}
+
+// groupRemove chameleon group rm <endpoint>
+func groupRemove(args []string) error {
+	fs, dataDir := newFlagSet("group rm <endpoint>")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := positional(rest, "<endpoint>"); err != nil {
+		return err
+	}
+	mgr, err := loadChannels(dataDir())
+	if err != nil {
+		return err
+	}
+	if err := mgr.DeleteGroup(rest[0]); err != nil {
+		return err
+	}
+	if err := mgr.SaveToFile(); err != nil {
+		return err
+	}
+	fmt.Printf("已删除渠道组 %s\n", rest[0])
+	return nil
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/config"
+	"github.com/sbgayhub/chameleon/backend/transport"
+)
+
+// subcommand 二级子命令，如 group add
+type subcommand struct {
+	usage string
+	run   func(args []string) error
+}
+
+// dispatch 执行二级子命令
+func dispatch(name string, subs map[string]subcommand, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Printf("用法: chameleon %s <子命令> [参数]\n\n子命令:\n", name)
+		names := make([]string, 0, len(subs))
+		for key := range subs {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+		for _, key := range names {
+			fmt.Printf("  %-10s %s\n", key, subs[key].usage)
+		}
+		return nil
+	}
+	sub, ok := subs[args[0]]
+	if !ok {
+		return fmt.Errorf("未知子命令: %s %s", name, args[0])
+	}
+	return sub.run(args[1:])
+}
+
+// loadChannels 加载数据目录中的渠道配置，测试渠道和获取模型列表时使用配置中的出站代理和超时
+func loadChannels(dataDir string) (*channel.Manager, error) {
+	configMgr, err := config.Open(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	transport.Setup(configMgr)
+
+	mgr := channel.NewManager(dataDir)
+	if err := mgr.LoadFromFile(); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+// lbStrategies 负载均衡策略名称
+var lbStrategies = map[string]channel.LBStrategy{
+	"priority": channel.LB_PRIORITY,
+	"round":    channel.LB_ROUND,
+	"weighted": channel.LB_WEIGHTED_ROUND,
+	"random":   channel.LB_RANDOM,
+}
+
+// lbName 获取负载均衡策略名称
+func lbName(strategy channel.LBStrategy) string {
+	for name, value := range lbStrategies {
+		if value == strategy {
+			return name
+		}
+	}
+	return fmt.Sprintf("unknown(%d)", strategy)
+}
+
+// statusName 获取渠道状态名称
+func statusName(status channel.Status) string {
+	switch status {
+	case channel.STATUS_NORMAL:
+		return "normal"
+	case channel.STATUS_ERROR:
+		return "error"
+	case channel.STATUS_NOT_AVAILABLE:
+		return "unavailable"
+	default:
+		return "-"
+	}
+}
+
+// validProvider 校验供应商类型
+func validProvider(provider string) error {
+	switch provider {
+	case "anthropic", "openai", "gemini":
+		return nil
+	}
+	return fmt.Errorf("不支持的供应商类型: %s（可选 anthropic|openai|gemini）", provider)
+}
+
+// mappingFlag 可重复的模型映射参数，格式为 源模型=目标模型
+type mappingFlag map[string]string
+
+func (m mappingFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m mappingFlag) Set(value string) error {
+	key, target, ok := strings.Cut(value, "=")
+	if !ok || key == "" || target == "" {
+		return fmt.Errorf("模型映射格式应为 源模型=目标模型: %s", value)
+	}
+	m[key] = target
+	return nil
+}
+
+// listFlag 可重复的列表参数，也支持逗号分隔
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// parse 解析参数，允许参数与位置参数交错出现，返回位置参数
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+// positional 校验位置参数数量
+func positional(args []string, names ...string) error {
+	if len(args) != len(names) {
+		return fmt.Errorf("需要参数: %s", strings.Join(names, " "))
+	}
+	return nil
+}
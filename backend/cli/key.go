@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/apikey"
+	"github.com/sbgayhub/chameleon/backend/statistics"
+)
+
+func init() {
+	register(&command{name: "key", usage: "管理网关客户端密钥（add|list|limit|revoke|rm）", run: func(args []string) error {
+		return dispatch("key", map[string]subcommand{
+			"add":    {usage: "生成客户端密钥", run: keyAdd},
+			"list":   {usage: "列出客户端密钥及用量", run: keyList},
+			"limit":  {usage: "修改客户端密钥的访问限制", run: keyLimit},
+			"revoke": {usage: "吊销客户端密钥", run: keyRevoke},
+			"rm":     {usage: "删除客户端密钥", run: keyRemove},
+		}, args)
+	}})
+}
+
+// limitFlags 注册密钥访问限制参数
+func limitFlags(fs *flag.FlagSet) func() apikey.Limits {
+	var groups, models listFlag
+	fs.Var(&groups, "group", "允许访问的渠道组，可重复指定，默认不限制")
+	fs.Var(&models, "model", "允许请求的模型，支持通配符，可重复指定，默认不限制")
+	rpm := fs.Int("rpm", 0, "每分钟最大请求数，0 表示不限制")
+	budget := fs.Uint64("budget", 0, "token 总预算（输入+输出），0 表示不限制")
+	return func() apikey.Limits {
+		return apikey.Limits{AllowedGroups: groups, AllowedModels: models, RateLimit: *rpm, TokenBudget: *budget}
+	}
+}
+
+// keyAdd chameleon key add <name> [--group g] [--model m] [--rpm n] [--budget n]
+func keyAdd(args []string) error {
+	fs, dataDir := newFlagSet("key add <name>")
+	limits := limitFlags(fs)
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := positional(rest, "<name>"); err != nil {
+		return err
+	}
+	result, err := apikey.NewManager(dataDir()).Generate(rest[0], limits())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已生成客户端密钥 %s（%s），请妥善保存，密钥只显示一次：\n%s\n", result.Key.ID, result.Key.Name, result.Secret)
+	return nil
+}
+
+// keyList chameleon key list
+func keyList(args []string) error {
+	fs, dataDir := newFlagSet("key list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dir := dataDir()
+	usage := statistics.NewManager(dir).GetKeyStatistics()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tKEY\tCREATED\tREVOKED\tGROUPS\tMODELS\tRPM\tREQUESTS\tTOKENS\tBUDGET")
+	for _, key := range apikey.NewManager(dir).List() {
+		created := time.UnixMilli(key.Created).Format(time.DateTime)
+		var requests, tokens uint64
+		if stats, ok := usage[key.ID]; ok {
+			requests, tokens = stats.RequestCount, stats.InputToken+stats.OutputToken
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%s\t%s\t%d\t%d\t%s\n", key.ID, key.Name, key.Hint, created, key.Revoked,
+			orAll(strings.Join(key.AllowedGroups, ",")), orAll(strings.Join(key.AllowedModels, ",")),
+			orAll(limitString(uint64(key.RateLimit))), requests, tokens, orAll(limitString(key.TokenBudget)))
+	}
+	return w.Flush()
+}
+
+// keyLimit chameleon key limit <id> [--group g] [--model m] [--rpm n] [--budget n]，未指定的限制会被清除
+func keyLimit(args []string) error {
+	fs, dataDir := newFlagSet("key limit <id>")
+	limits := limitFlags(fs)
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := positional(rest, "<id>"); err != nil {
+		return err
+	}
+	if err := apikey.NewManager(dataDir()).SetLimits(rest[0], limits()); err != nil {
+		return err
+	}
+	fmt.Printf("已修改客户端密钥 %s 的访问限制\n", rest[0])
+	return nil
+}
+
+// keyRevoke chameleon key revoke <id>
+func keyRevoke(args []string) error {
+	fs, dataDir := newFlagSet("key revoke <id>")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := positional(rest, "<id>"); err != nil {
+		return err
+	}
+	if err := apikey.NewManager(dataDir()).Revoke(rest[0]); err != nil {
+		return err
+	}
+	fmt.Printf("已吊销客户端密钥 %s\n", rest[0])
+	return nil
+}
+
+// keyRemove chameleon key rm <id>
+func keyRemove(args []string) error {
+	fs, dataDir := newFlagSet("key rm <id>")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := positional(rest, "<id>"); err != nil {
+		return err
+	}
+	if err := apikey.NewManager(dataDir()).Delete(rest[0]); err != nil {
+		return err
+	}
+	fmt.Printf("已删除客户端密钥 %s\n", rest[0])
+	return nil
+}
+
+// limitString 格式化数量限制，0 表示不限制
+func limitString(n uint64) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprint(n)
+}
+
+// orAll 空限制显示为不限制
+func orAll(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
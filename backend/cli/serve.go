@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sbgayhub/chameleon/backend/config"
+	"github.com/sbgayhub/chameleon/backend/service"
+)
+
+func init() {
+	register(&command{name: "serve", usage: "以无界面模式运行代理服务", run: serve})
+}
+
+// serve 加载数据目录中的配置并启动代理，收到 SIGINT/SIGTERM 后优雅退出
+func serve(args []string) error {
+	fs, dataDir := newFlagSet("serve")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dir := dataDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %w", err)
+	}
+
+	// 配置文件无效时直接退出，避免以默认配置运行
+	if _, err := config.Open(dir); err != nil {
+		return fmt.Errorf("加载配置文件失败: %w", err)
+	}
+
+	svc := service.New(dir)
+
+	// 无界面模式下日志始终输出到标准输出
+	logConfig := *svc.ConfigMgr.GetConfig().Log
+	logConfig.Console = true
+	config.InitLogger(dir, &logConfig)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return svc.Serve(ctx)
+}
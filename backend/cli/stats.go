@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/config"
+	"github.com/sbgayhub/chameleon/backend/statistics"
+)
+
+func init() {
+	register(&command{name: "stats", usage: "查看或重置统计数据（show|reset）", run: func(args []string) error {
+		return dispatch("stats", map[string]subcommand{
+			"show":  {usage: "显示各渠道的统计数据", run: statsShow},
+			"reset": {usage: "清空所有统计数据", run: statsReset},
+		}, args)
+	}})
+}
+
+// statsShow chameleon stats show [--json]
+func statsShow(args []string) error {
+	fs, dataDir := newFlagSet("stats show")
+	asJSON := fs.Bool("json", false, "以 JSON 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	mgr := statistics.NewManager(dataDir())
+	defer mgr.Close()
+
+	all := mgr.GetAllStatistics()
+	total := mgr.GetTotalStatistics()
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(map[string]any{"channels": all, "total": total, "daily": mgr.GetDailyStatistics()})
+	}
+
+	rows := make([]*statistics.Statistics, 0, len(all)+1)
+	for _, stats := range all {
+		rows = append(rows, stats)
+	}
+	slices.SortFunc(rows, func(a, b *statistics.Statistics) int {
+		return strings.Compare(a.ChannelName, b.ChannelName)
+	})
+	rows = append(rows, total)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL\tREQUESTS\tSUCCESS\tFAILURE\tABORTED\tINPUT TOKENS\tOUTPUT TOKENS\tLAST USED")
+	for _, stats := range rows {
+		lastUsed := "-"
+		if !stats.LastUsed.IsZero() {
+			lastUsed = stats.LastUsed.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n", stats.ChannelName, stats.RequestCount, stats.SuccessCount,
+			stats.FailureCount, stats.AbortedCount, stats.InputToken, stats.OutputToken, lastUsed)
+	}
+	return w.Flush()
+}
+
+// statsReset chameleon stats reset
+func statsReset(args []string) error {
+	fs, dataDir := newFlagSet("stats reset")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dir := dataDir()
+	// 界面或 serve 正在运行时统计数据以其内存为准，直接改写文件会被下次落盘覆盖，改为通过管理接口重置
+	if statistics.InUse(dir) {
+		if err := resetViaAdmin(dir); err != nil {
+			return err
+		}
+		fmt.Println("已通过管理接口清空正在运行实例的统计数据")
+		return nil
+	}
+
+	mgr := statistics.NewManager(dir)
+	mgr.ResetAllStatistics()
+	if err := mgr.Close(); err != nil {
+		return err
+	}
+	fmt.Println("统计数据已清空")
+	return nil
+}
+
+// resetViaAdmin 调用正在运行实例的管理接口重置统计数据，未启用管理接口时拒绝重置
+func resetViaAdmin(dataDir string) error {
+	configMgr, err := config.Open(dataDir)
+	if err != nil {
+		return err
+	}
+	cfg := configMgr.GetConfig().Admin
+	if cfg == nil || !cfg.Enabled || cfg.Token == "" {
+		return fmt.Errorf("Chameleon 正在运行，请在界面中重置统计，或在 config.toml 中启用 [admin] 管理接口后重试")
+	}
+
+	// 监听所有地址时通过本地回环访问
+	host, port, err := net.SplitHostPort(cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("管理接口监听地址不合法: %w", err)
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	request, err := http.NewRequest(http.MethodDelete, "http://"+net.JoinHostPort(host, port)+"/api/stats", nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+cfg.Token)
+
+	response, err := (&http.Client{Timeout: 10 * time.Second}).Do(request)
+	if err != nil {
+		return fmt.Errorf("Chameleon 正在运行，通过管理接口重置统计失败: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("Chameleon 正在运行，管理接口返回 %s", response.Status)
+	}
+	return nil
+}
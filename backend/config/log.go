@@ -9,6 +9,11 @@ import (
 	"github.com/phsym/console-slog"
 )
 
+// LogPath 日志文件路径
+func LogPath(dataDir string) string {
+	return filepath.Join(dataDir, "logs", "app.log")
+}
+
 // InitLogger 初始化日志系统
 func InitLogger(dataDir string, config *LogConfig) {
 	var level slog.Level
@@ -25,7 +30,7 @@ func InitLogger(dataDir string, config *LogConfig) {
 
 	if config.Console && config.File {
 		// 同时输出到控制台和文件
-		logFile := filepath.Join(dataDir, "logs", "app.log")
+		logFile := LogPath(dataDir)
 		_ = os.MkdirAll(filepath.Dir(logFile), 0755)
 
 		// 打开日志文件
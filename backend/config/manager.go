@@ -1,48 +1,129 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pelletier/go-toml/v2"
 )
 
-// NewManager 创建配置管理器
-func NewManager(dataDir string) *Manager {
+// NewManager 创建配置管理器。配置文件无效时使用默认配置并返回错误，由调用方提示用户，
+// 原文件保留到首次保存时备份为 config.toml.invalid，避免被默认配置覆盖
+func NewManager(dataDir string) (*Manager, error) {
 	configPath := filepath.Join(dataDir, "config.toml")
+	manager := newManager(configPath)
 
-	manager := &Manager{
-		configPath: configPath,
-		config:     getDefaultConfig(),
-	}
-
-	// 启动时加载配置，失败则创建默认配置
-	if err := manager.Load(); err != nil {
-		slog.Warn("加载配置失败，使用默认配置", "error", err)
+	err := manager.Load()
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		slog.Info("配置文件不存在，使用默认配置", "path", configPath)
 		_ = manager.Save()
+		err = nil
+	default:
+		manager.invalid = true
+		err = fmt.Errorf("配置文件 %s 无效，当前使用默认配置: %w", configPath, err)
 	}
 
 	InitLogger(dataDir, manager.config.Log)
+	if err != nil {
+		slog.Error("加载配置失败", "error", err)
+	}
+	return manager, err
+}
 
-	return manager
+// Open 加载数据目录中的配置，不初始化日志，供命令行工具使用；配置文件不存在时使用默认配置
+func Open(dataDir string) (*Manager, error) {
+	manager := newManager(filepath.Join(dataDir, "config.toml"))
+	if err := manager.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return manager, nil
 }
 
-// Load 加载配置文件
+func newManager(configPath string) *Manager {
+	return &Manager{
+		configPath:  configPath,
+		config:      getDefaultConfig(),
+		subscribers: make(map[chan *ProxyConfig]struct{}),
+	}
+}
+
+// Load 加载配置文件，旧版本的配置迁移到当前版本并备份原文件，缺失的配置段使用默认值，校验失败时返回错误
 func (m *Manager) Load() error {
 	data, err := os.ReadFile(m.configPath)
 	if err != nil {
 		return err
 	}
 
-	return toml.Unmarshal(data, m.config)
+	doc := make(map[string]any)
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	version, err := migrate(doc)
+	if err != nil {
+		return err
+	}
+	migrated, err := toml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	config := getDefaultConfig()
+	if err := toml.NewDecoder(bytes.NewReader(migrated)).DisallowUnknownFields().Decode(config); err != nil {
+		var strict *toml.StrictMissingError
+		if !errors.As(err, &strict) {
+			return fmt.Errorf("解析配置文件失败: %w", err)
+		}
+		// 其余配置项已正常解析，无法识别的配置项在下次保存时会被移除
+		keys := make([]string, 0, len(strict.Errors))
+		for _, e := range strict.Errors {
+			keys = append(keys, strings.Join(e.Key(), "."))
+		}
+		slog.Warn("配置文件中存在无法识别的配置项，将被忽略", "keys", keys)
+	}
+	config.applyDefaults()
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("配置校验失败: %w", err)
+	}
+	m.config = config
+
+	if version < SchemaVersion {
+		backup := fmt.Sprintf("%s.v%d.bak", m.configPath, version)
+		if err := os.WriteFile(backup, data, 0644); err != nil {
+			return fmt.Errorf("备份旧版本配置失败: %w", err)
+		}
+		slog.Info("配置文件已升级", "from", version, "to", SchemaVersion, "backup", backup)
+		return m.Save()
+	}
+	return nil
 }
 
 // Save 保存配置文件
 func (m *Manager) Save() error {
+	return m.save(m.config)
+}
+
+// save 将配置写入配置文件，加载失败的原配置文件先备份
+func (m *Manager) save(config *Config) error {
 	_ = os.MkdirAll(filepath.Dir(m.configPath), 0755)
 
-	data, err := toml.Marshal(m.config)
+	if m.invalid {
+		backup := m.configPath + ".invalid"
+		if err := os.Rename(m.configPath, backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("备份无效的配置文件失败: %w", err)
+		}
+		slog.Warn("无效的配置文件已备份", "backup", backup)
+		m.invalid = false
+	}
+
+	config.SchemaVersion = SchemaVersion
+	data, err := toml.Marshal(config)
 	if err != nil {
 		return err
 	}
@@ -55,34 +136,116 @@ func (m *Manager) GetConfig() *Config {
 	return m.config
 }
 
-// UpdateConfig 更新配置
+// Clone 深拷贝配置，修改副本不影响当前配置
+func (c *Config) Clone() *Config {
+	clone := &Config{}
+	if data, err := toml.Marshal(c); err == nil {
+		_ = toml.Unmarshal(data, clone)
+	}
+	return clone
+}
+
+// UpdateConfig 更新配置，缺失的配置段使用默认值，校验或保存失败时不做修改
 func (m *Manager) UpdateConfig(config *Config) error {
+	config.applyDefaults()
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if err := m.save(config); err != nil {
+		return err
+	}
 	m.config = config
-	return m.Save()
+	m.publish(config.Proxy)
+	return nil
 }
 
-// UpdateProxyConfig 更新代理配置
+// UpdateProxyConfig 更新代理配置，保存成功后通知订阅者
 func (m *Manager) UpdateProxyConfig(proxy *ProxyConfig) error {
-	m.config.Proxy = proxy
-	return m.Save()
+	if err := m.update(func(config *Config) { config.Proxy = proxy }); err != nil {
+		return err
+	}
+	m.publish(m.config.Proxy)
+	return nil
+}
+
+// update 在配置的深拷贝上应用修改，补全默认值并校验、保存成功后替换当前配置
+func (m *Manager) update(fn func(config *Config)) error {
+	config := m.config.Clone()
+	fn(config)
+	config.applyDefaults()
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if err := m.save(config); err != nil {
+		return err
+	}
+	m.config = config
+	return nil
+}
+
+// SubscribeProxy 订阅代理配置变更，返回配置通道和取消订阅函数，订阅者处理不及时时只保留最新的配置
+func (m *Manager) SubscribeProxy() (<-chan *ProxyConfig, func()) {
+	ch := make(chan *ProxyConfig, 1)
+	m.subMu.Lock()
+	m.subscribers[ch] = struct{}{}
+	m.subMu.Unlock()
+
+	return ch, func() {
+		m.subMu.Lock()
+		defer m.subMu.Unlock()
+		if _, ok := m.subscribers[ch]; ok {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish 通知订阅者代理配置已更新，未处理的旧配置会被替换
+func (m *Manager) publish(proxy *ProxyConfig) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	for ch := range m.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- proxy
+	}
 }
 
 // UpdateGeneralConfig 更新通用配置
 func (m *Manager) UpdateGeneralConfig(general *GeneralConfig) error {
-	m.config.General = general
-	return m.Save()
+	return m.update(func(config *Config) { config.General = general })
 }
 
 // UpdateUIConfig 更新UI配置
 func (m *Manager) UpdateUIConfig(ui *UIConfig) error {
-	m.config.UI = ui
-	return m.Save()
+	return m.update(func(config *Config) { config.UI = ui })
 }
 
 // UpdateLogConfig 更新日志配置
 func (m *Manager) UpdateLogConfig(log *LogConfig) error {
-	m.config.Log = log
-	return m.Save()
+	return m.update(func(config *Config) { config.Log = log })
+}
+
+// UpdateRecordConfig 更新请求记录配置
+func (m *Manager) UpdateRecordConfig(record *RecordConfig) error {
+	return m.update(func(config *Config) { config.Record = record })
+}
+
+// UpdateAdminConfig 更新管理接口配置
+func (m *Manager) UpdateAdminConfig(admin *AdminConfig) error {
+	return m.update(func(config *Config) { config.Admin = admin })
+}
+
+// UpdateTelemetryConfig 更新监控配置
+func (m *Manager) UpdateTelemetryConfig(telemetry *TelemetryConfig) error {
+	return m.update(func(config *Config) { config.Telemetry = telemetry })
+}
+
+// UpdateOutboundConfig 更新出站请求配置
+func (m *Manager) UpdateOutboundConfig(outbound *OutboundConfig) error {
+	return m.update(func(config *Config) { config.Outbound = outbound })
 }
 
 //
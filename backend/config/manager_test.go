@@ -0,0 +1,223 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 没有 schema_version 的旧配置迁移到当前版本，原文件备份为 .v0.bak
+func TestLoadMigratesLegacyConfig(t *testing.T) {
+	dir := t.TempDir()
+	legacy := "[proxy]\nmode = \"http\"\nport = 8080\n"
+	path := writeConfig(t, dir, legacy)
+
+	mgr, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := mgr.GetConfig().Proxy.Port; got != 8080 {
+		t.Errorf("Proxy.Port = %d, want 8080", got)
+	}
+
+	backup, err := os.ReadFile(path + ".v0.bak")
+	if err != nil {
+		t.Fatalf("读取备份失败: %v", err)
+	}
+	if string(backup) != legacy {
+		t.Errorf("备份内容 = %q, want %q", backup, legacy)
+	}
+
+	reloaded, err := Open(dir)
+	if err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if got := reloaded.GetConfig().SchemaVersion; got != SchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", got, SchemaVersion)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "无法识别的配置项被忽略", content: "schema_version = 1\nunknown = true\n[proxy]\nport = 8080\nlegacy = 1\n"},
+		{name: "版本高于当前程序", content: "schema_version = 99\n", wantErr: "高于当前程序支持的版本"},
+		{name: "版本不合法", content: "schema_version = \"1\"\n", wantErr: "schema_version"},
+		{name: "格式错误", content: "[proxy\n", wantErr: "解析配置文件失败"},
+		{name: "校验失败", content: "schema_version = 1\n[proxy]\nmode = \"unknown\"\n", wantErr: "配置校验失败"},
+		{name: "代理端口为 0", content: "schema_version = 1\n[proxy]\nport = 0\n", wantErr: "proxy.port: 端口不能为 0"},
+		{name: "Host 端口为 0", content: "schema_version = 1\n[proxy.host]\nport = 0\n", wantErr: "proxy.host.port: 端口不能为 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfig(t, dir, tt.content)
+			_, err := Open(dir)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Open() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Open() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 配置文件无效时返回错误并使用默认配置，原文件在首次保存时才备份
+func TestNewManagerWithInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	invalid := "[proxy\n"
+	path := writeConfig(t, dir, invalid)
+
+	mgr, err := NewManager(dir)
+	if err == nil {
+		t.Fatal("NewManager() error = nil, want error")
+	}
+	if got, want := mgr.GetConfig().Proxy.Port, getDefaultConfig().Proxy.Port; got != want {
+		t.Errorf("Proxy.Port = %d, want %d", got, want)
+	}
+	if data, _ := os.ReadFile(path); string(data) != invalid {
+		t.Fatalf("保存前配置文件被修改: %q", data)
+	}
+
+	if err := mgr.UpdateUIConfig(&UIConfig{Theme: "dark"}); err != nil {
+		t.Fatalf("UpdateUIConfig() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path + ".invalid"); string(data) != invalid {
+		t.Errorf("备份内容 = %q, want %q", data, invalid)
+	}
+	if _, err := Open(dir); err != nil {
+		t.Errorf("保存后的配置无法加载: %v", err)
+	}
+}
+
+// 留空的配置项与运行时使用相同的默认值，端口只在配置段缺失时填充
+func TestApplyDefaults(t *testing.T) {
+	config := &Config{Proxy: &ProxyConfig{Port: 9000, Host: &HostConfig{Method: "dns", Port: 8443}}}
+	config.applyDefaults()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	host := config.Proxy.Host
+	if host.Method != "dns" {
+		t.Errorf("Host.Method = %q, want dns", host.Method)
+	}
+	if host.Listen != "127.0.0.1" || host.Port != 8443 {
+		t.Errorf("Host = %s:%d, want 127.0.0.1:8443", host.Listen, host.Port)
+	}
+	if host.DNSListen == "" {
+		t.Error("Host.DNSListen 未填充默认值")
+	}
+	if config.Proxy.Gateway.Listen != "127.0.0.1" || config.Proxy.Gateway.GroupHeader == "" {
+		t.Errorf("Gateway = %+v, 未填充默认值", config.Proxy.Gateway)
+	}
+	if len(config.Proxy.Resolver.Servers) == 0 {
+		t.Error("Resolver.Servers 未填充默认值")
+	}
+	if config.Outbound.ConnectTimeout == 0 || config.Outbound.MaxIdleConnsPerHost == 0 {
+		t.Errorf("Outbound = %+v, 未填充默认值", config.Outbound)
+	}
+
+	missing := &Config{}
+	missing.applyDefaults()
+	if missing.Proxy.Port != 9527 || missing.Proxy.Host.Port != 443 {
+		t.Errorf("缺失配置段时端口 = %d/%d, want 9527/443", missing.Proxy.Port, missing.Proxy.Host.Port)
+	}
+}
+
+// 配置文件中未填写端口时使用默认端口
+func TestLoadKeepsDefaultPorts(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "schema_version = 1\n[proxy]\nmode = \"http\"\n[proxy.host]\nmethod = \"hosts\"\n")
+	mgr, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if proxy := mgr.GetConfig().Proxy; proxy.Port != 9527 || proxy.Host.Port != 443 {
+		t.Errorf("端口 = %d/%d, want 9527/443", proxy.Port, proxy.Host.Port)
+	}
+}
+
+// 更新失败时不修改当前配置中的任何配置段
+func TestUpdateDoesNotMutateLiveConfig(t *testing.T) {
+	mgr := newManager(filepath.Join(t.TempDir(), "config.toml"))
+	live := mgr.GetConfig()
+	live.UI.Language = ""
+	live.Proxy.Gateway.Listen = ""
+
+	if err := mgr.UpdateLogConfig(&LogConfig{Level: "verbose"}); err == nil {
+		t.Fatal("UpdateLogConfig() error = nil, want error")
+	}
+	if mgr.GetConfig() != live {
+		t.Fatal("校验失败后配置被替换")
+	}
+	if live.UI.Language != "" || live.Proxy.Gateway.Listen != "" {
+		t.Errorf("当前配置被修改: language = %q, gateway.listen = %q", live.UI.Language, live.Proxy.Gateway.Listen)
+	}
+}
+
+// 保存失败时不修改当前配置，也不通知订阅者
+func TestUpdateProxyConfigSaveFailure(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mgr := newManager(filepath.Join(blocker, "config.toml"))
+	mgr.config.applyDefaults()
+	before := mgr.GetConfig().Proxy
+
+	updates, unsubscribe := mgr.SubscribeProxy()
+	defer unsubscribe()
+
+	proxy := *before
+	proxy.Port = 8080
+	if err := mgr.UpdateProxyConfig(&proxy); err == nil {
+		t.Fatal("UpdateProxyConfig() error = nil, want error")
+	}
+	if mgr.GetConfig().Proxy != before {
+		t.Error("保存失败后代理配置被修改")
+	}
+	select {
+	case got := <-updates:
+		t.Errorf("保存失败后通知了订阅者: port %d", got.Port)
+	default:
+	}
+}
+
+func TestUpdateProxyConfigPublishes(t *testing.T) {
+	mgr := newManager(filepath.Join(t.TempDir(), "config.toml"))
+	updates, unsubscribe := mgr.SubscribeProxy()
+	defer unsubscribe()
+
+	proxy := *mgr.GetConfig().Proxy
+	proxy.Port = 8080
+	if err := mgr.UpdateProxyConfig(&proxy); err != nil {
+		t.Fatalf("UpdateProxyConfig() error = %v", err)
+	}
+	select {
+	case got := <-updates:
+		if got.Port != 8080 {
+			t.Errorf("Port = %d, want 8080", got.Port)
+		}
+	default:
+		t.Error("未通知订阅者")
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import "fmt"
+
+// SchemaVersion 当前 config.toml 的结构版本，修改配置结构导致旧配置无法直接读取时递增，并在 migrations 中注册迁移
+const SchemaVersion = 1
+
+// migrations 配置迁移，migrations[i] 将版本 i 的配置升级到版本 i+1，在解析为 Config 之前对原始键值进行修改
+var migrations = []func(doc map[string]any) error{
+	// 0 -> 1：旧版本没有 schema_version，结构与版本 1 相同
+	func(map[string]any) error { return nil },
+}
+
+// migrate 将原始配置升级到当前版本，返回原始版本，配置版本高于当前程序时返回错误
+func migrate(doc map[string]any) (int, error) {
+	version, err := schemaVersion(doc)
+	if err != nil {
+		return 0, err
+	}
+	if version > SchemaVersion {
+		return version, fmt.Errorf("配置文件版本 %d 高于当前程序支持的版本 %d，请升级程序", version, SchemaVersion)
+	}
+	for v := version; v < SchemaVersion; v++ {
+		if err := migrations[v](doc); err != nil {
+			return version, fmt.Errorf("配置从版本 %d 迁移到 %d 失败: %w", v, v+1, err)
+		}
+	}
+	doc["schema_version"] = int64(SchemaVersion)
+	return version, nil
+}
+
+// schemaVersion 读取配置中的 schema_version，不存在时为 0
+func schemaVersion(doc map[string]any) (int, error) {
+	value, ok := doc["schema_version"]
+	if !ok {
+		return 0, nil
+	}
+	version, ok := value.(int64)
+	if !ok || version < 0 {
+		return 0, fmt.Errorf("schema_version: 不合法的版本 %v", value)
+	}
+	return int(version), nil
+}
@@ -1,18 +1,56 @@
 package config
 
+import "sync"
+
 // Config 配置结构体
 type Config struct {
-	General *GeneralConfig `toml:"general" comment:"通用配置"`
-	UI      *UIConfig      `toml:"ui" comment:"UI配置"`
-	Proxy   *ProxyConfig   `toml:"proxy" comment:"代理配置"`
-	Log     *LogConfig     `toml:"log" comment:"日志配置"`
+	SchemaVersion int              `toml:"schema_version" comment:"配置文件结构版本，用于升级时迁移配置，请勿手动修改"`
+	General       *GeneralConfig   `toml:"general" comment:"通用配置"`
+	UI            *UIConfig        `toml:"ui" comment:"UI配置"`
+	Proxy         *ProxyConfig     `toml:"proxy" comment:"代理配置"`
+	Log           *LogConfig       `toml:"log" comment:"日志配置"`
+	Record        *RecordConfig    `toml:"record" comment:"请求记录配置"`
+	Admin         *AdminConfig     `toml:"admin" comment:"本地管理接口配置"`
+	Telemetry     *TelemetryConfig `toml:"telemetry" comment:"监控指标与链路追踪配置"`
+	Outbound      *OutboundConfig  `toml:"outbound" comment:"出站请求配置"`
 }
 
 // ProxyConfig 代理配置
 type ProxyConfig struct {
-	Mode          string `toml:"mode" comment:"代理模式：http|host"` // 代理模式：http/socks/host
-	Port          uint16 `toml:"port" comment:"http代理服务器监听端口"`  // 服务器监听端口，非host模式可配置
-	CertInstalled bool   `toml:"cert_installed" comment:"CA 证书安装状态"`
+	Mode          string          `toml:"mode" comment:"代理模式：http|socks|host|gateway"` // 代理模式：http/socks/host/gateway
+	Port          uint16          `toml:"port" comment:"http代理服务器监听端口"`                // 服务器监听端口，非host模式可配置
+	CertInstalled bool            `toml:"cert_installed" comment:"CA 证书安装状态"`
+	Gateway       *GatewayConfig  `toml:"gateway" comment:"网关模式配置"`
+	Resolver      *ResolverConfig `toml:"resolver" comment:"Host 模式出站域名解析配置"`
+	Host          *HostConfig     `toml:"host" comment:"Host 模式配置"`
+	Cert          *CertConfig     `toml:"cert" comment:"站点证书签发配置"`
+}
+
+// CertConfig 站点证书签发配置
+type CertConfig struct {
+	KeyType   string `toml:"key_type" comment:"站点证书密钥类型：ecdsa（P-256，签发快）|rsa（兼容不支持 ECDSA 的旧客户端）"` // 站点证书密钥类型
+	CacheSize int    `toml:"cache_size" comment:"内存中最多缓存的站点证书数量，0 使用默认值 1000"`                    // 缓存容量
+	Persist   bool   `toml:"persist" comment:"是否将站点证书保存到数据目录 certs 下，重启后无需重新签发"`                  // 是否持久化
+}
+
+// HostConfig Host 模式配置
+type HostConfig struct {
+	Method    string `toml:"method" comment:"劫持方式：hosts 修改系统 hosts 文件（需要管理员权限）|dns 启动本地 DNS 服务，只将渠道组域名解析到本机"` // 劫持方式
+	Listen    string `toml:"listen" comment:"HTTPS 监听地址，需为回环地址"`                                              // HTTPS 监听地址
+	Port      uint16 `toml:"port" comment:"HTTPS 监听端口，非 443 时需开启 redirect"`                                   // HTTPS 监听端口
+	DNSListen string `toml:"dns_listen" comment:"本地 DNS 服务监听地址，dns 方式时需将系统 DNS 设置为该地址"`                       // DNS 监听地址
+	Redirect  bool   `toml:"redirect" comment:"Linux 下使用 nftables/iptables 将回环地址的 443、53 端口重定向到监听端口"`         // 是否添加端口重定向规则
+}
+
+// ResolverConfig Host 模式出站域名解析配置，绕过被改写的 hosts 文件直接查询 DNS
+type ResolverConfig struct {
+	Servers []string `toml:"servers" comment:"DNS 服务器，按顺序尝试；https:// 开头为 DNS-over-HTTPS，其他为 IP[:端口] 的普通 DNS"` // DNS 服务器列表
+}
+
+// GatewayConfig 网关模式配置，客户端直接将 base url 设置为网关地址，无需信任证书
+type GatewayConfig struct {
+	Listen      string `toml:"listen" comment:"网关监听地址，团队共享时可设置为 0.0.0.0"`         // 监听地址
+	GroupHeader string `toml:"group_header" comment:"指定渠道组的请求头，未指定时按路径前缀或请求格式选择"` // 指定渠道组的请求头
 }
 
 // GeneralConfig 通用配置
@@ -39,10 +77,65 @@ type LogConfig struct {
 	Console bool   `toml:"console" comment:"是否输出到控制台"` // 是否输出到控制台
 }
 
+// RecordConfig 请求记录配置
+type RecordConfig struct {
+	Enabled       bool `toml:"enabled" comment:"是否记录每次请求"`                     // 是否记录每次请求
+	RetentionDays int  `toml:"retention_days" comment:"请求记录保留天数，0表示不限制"`       // 保留天数
+	MaxRecords    int  `toml:"max_records" comment:"最多保留的请求记录条数，0表示不限制"`       // 最大条数
+	Capture       bool `toml:"capture" comment:"是否保存完整的请求和响应内容，用于排查转换问题"`      // 完整内容捕获
+	CaptureBodyKB int  `toml:"capture_body_kb" comment:"单个请求或响应内容最多保存的大小(KB)"` // 单个内容大小上限
+	CaptureMaxMB  int  `toml:"capture_max_mb" comment:"捕获内容总共最多占用的存储空间(MB)"`   // 总存储上限
+}
+
+// AdminConfig 本地管理接口配置
+type AdminConfig struct {
+	Enabled bool   `toml:"enabled" comment:"是否启用本地管理接口"`          // 是否启用
+	Listen  string `toml:"listen" comment:"管理接口监听地址，建议仅监听本地回环地址"` // 监听地址
+	Token   string `toml:"token" comment:"管理接口访问令牌，为空时首次启动自动生成"`  // 访问令牌
+}
+
+// TelemetryConfig 监控指标与链路追踪配置
+type TelemetryConfig struct {
+	Metrics      bool   `toml:"metrics" comment:"是否在管理接口上提供 Prometheus /metrics 指标"`      // Prometheus 指标
+	Tracing      bool   `toml:"tracing" comment:"是否启用 OpenTelemetry 链路追踪"`                // 链路追踪
+	OTLPEndpoint string `toml:"otlp_endpoint" comment:"OTLP/HTTP 导出地址，例如 localhost:4318"` // OTLP 导出地址
+	ServiceName  string `toml:"service_name" comment:"链路追踪中的服务名称"`                        // 服务名称
+}
+
+// OutboundConfig 出站请求配置，作用于发往渠道的请求、直接转发的请求和渠道测试
+type OutboundConfig struct {
+	Proxy   string   `toml:"proxy" comment:"全局上游代理，支持 http://、https://、socks5://，可包含 user:pass@ 认证信息；为空时使用系统代理环境变量，direct 表示直连"` // 全局上游代理
+	NoProxy []string `toml:"no_proxy" comment:"不经过上游代理的地址，支持域名（.example.com 匹配子域名）、IP 和 CIDR"`                                   // 不经过代理的地址
+
+	ConnectTimeout        uint `toml:"connect_timeout" comment:"建立连接超时（秒），0 使用默认值 10"`                        // 建立 TCP 连接超时
+	TLSTimeout            uint `toml:"tls_timeout" comment:"TLS 握手超时（秒），0 使用默认值 10"`                          // TLS 握手超时
+	ResponseHeaderTimeout uint `toml:"response_header_timeout" comment:"等待响应头超时（秒），非流式请求需等待生成完成，0 使用默认值 600"` // 发出请求后等待响应头超时
+	StreamIdleTimeout     uint `toml:"stream_idle_timeout" comment:"响应体两次读取之间的最长间隔（秒），0 使用默认值 300"`           // 响应体空闲超时，只要持续有数据就不会中断
+	MaxIdleConnsPerHost   int  `toml:"max_idle_conns_per_host" comment:"每个渠道对每个主机保留的空闲连接数，0 使用默认值 16"`        // 每个主机的空闲连接数
+}
+
+// DefaultHostConfig Host 模式默认配置，与旧版本行为一致
+func DefaultHostConfig() *HostConfig {
+	return &HostConfig{Method: "hosts", Listen: "127.0.0.1", Port: 443, DNSListen: "127.0.0.1:5353"}
+}
+
+// DefaultCertConfig 站点证书签发默认配置
+func DefaultCertConfig() *CertConfig {
+	return &CertConfig{KeyType: "ecdsa", CacheSize: 1000}
+}
+
+// DefaultDNSServers Host 模式默认使用的 DNS 服务器
+func DefaultDNSServers() []string {
+	return []string{"https://223.5.5.5/dns-query", "https://1.1.1.1/dns-query", "119.29.29.29:53", "8.8.8.8:53"}
+}
+
 // Manager 配置管理器
 type Manager struct {
-	configPath string
-	config     *Config
+	configPath  string
+	config      *Config
+	invalid     bool                           // 配置文件无法加载，保存前需先备份
+	subscribers map[chan *ProxyConfig]struct{} // 代理配置变更订阅者
+	subMu       sync.Mutex
 }
 
 // getDefaultConfig 返回默认配置
@@ -64,11 +157,47 @@ func getDefaultConfig() *Config {
 		Proxy: &ProxyConfig{
 			Mode: "http",
 			Port: 9527,
+			Gateway: &GatewayConfig{
+				Listen:      "127.0.0.1",
+				GroupHeader: "X-Chameleon-Group",
+			},
+			Resolver: &ResolverConfig{
+				Servers: DefaultDNSServers(),
+			},
+			Host: DefaultHostConfig(),
+			Cert: DefaultCertConfig(),
 		},
 		Log: &LogConfig{
 			Level:   "debug",
 			File:    true,
 			Console: true,
 		},
+		Record: &RecordConfig{
+			Enabled:       true,
+			RetentionDays: 30,
+			MaxRecords:    100000,
+			Capture:       false,
+			CaptureBodyKB: 1024,
+			CaptureMaxMB:  200,
+		},
+		Admin: &AdminConfig{
+			Enabled: false,
+			Listen:  "127.0.0.1:9528",
+		},
+		Telemetry: &TelemetryConfig{
+			Metrics:      true,
+			Tracing:      false,
+			OTLPEndpoint: "localhost:4318",
+			ServiceName:  "chameleon",
+		},
+		Outbound: &OutboundConfig{
+			Proxy:                 "",
+			NoProxy:               []string{},
+			ConnectTimeout:        10,
+			TLSTimeout:            10,
+			ResponseHeaderTimeout: 600,
+			StreamIdleTimeout:     300,
+			MaxIdleConnsPerHost:   16,
+		},
 	}
 }
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+var (
+	proxyModes   = []string{"http", "socks", "host", "gateway"}
+	hostMethods  = []string{"hosts", "dns"}
+	certKeyTypes = []string{"ecdsa", "rsa"}
+	closeActions = []string{"ask", "minimize", "exit"}
+	logLevels    = []string{"debug", "info", "warn", "error"}
+)
+
+// ValidationError 配置项校验错误，Field 为配置文件中的键路径，如 proxy.port
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Validate 校验配置，返回所有不合法的配置项，需先调用 applyDefaults 补全缺失的配置段
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...any) {
+		errs = append(errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	oneOf := func(field, value string, options []string) {
+		if !slices.Contains(options, value) {
+			invalid(field, "不支持的值 %q，可选 %s", value, strings.Join(options, "|"))
+		}
+	}
+
+	oneOf("general.close_action", c.General.CloseAction, closeActions)
+	oneOf("log.level", c.Log.Level, logLevels)
+
+	oneOf("proxy.mode", c.Proxy.Mode, proxyModes)
+	if c.Proxy.Port == 0 {
+		invalid("proxy.port", "端口不能为 0")
+	}
+	oneOf("proxy.host.method", c.Proxy.Host.Method, hostMethods)
+	if c.Proxy.Host.Port == 0 {
+		invalid("proxy.host.port", "端口不能为 0")
+	}
+	if net.ParseIP(c.Proxy.Host.Listen) == nil {
+		invalid("proxy.host.listen", "%q 不是合法的 IP 地址", c.Proxy.Host.Listen)
+	}
+	if _, port, err := net.SplitHostPort(c.Proxy.Host.DNSListen); c.Proxy.Host.Method == "dns" && (err != nil || port == "0") {
+		invalid("proxy.host.dns_listen", "%q 不是合法的 IP:端口", c.Proxy.Host.DNSListen)
+	}
+	oneOf("proxy.cert.key_type", c.Proxy.Cert.KeyType, certKeyTypes)
+	if c.Proxy.Cert.CacheSize < 0 {
+		invalid("proxy.cert.cache_size", "不能为负数")
+	}
+
+	if c.Record.RetentionDays < 0 {
+		invalid("record.retention_days", "不能为负数")
+	}
+	if c.Record.MaxRecords < 0 {
+		invalid("record.max_records", "不能为负数")
+	}
+	if c.Outbound.MaxIdleConnsPerHost < 0 {
+		invalid("outbound.max_idle_conns_per_host", "不能为负数")
+	}
+	return errors.Join(errs...)
+}
+
+// applyDefaults 为缺失的配置段和留空的配置项填充默认值，与运行时对未配置项的处理保持一致；
+// 记录数量、保留天数等 0 表示不限制的配置项除外，端口只在整个配置段缺失时使用默认值，填写为 0 时由 Validate 报错
+func (c *Config) applyDefaults() {
+	defaults := getDefaultConfig()
+	if c.General == nil {
+		c.General = defaults.General
+	}
+	c.General.CloseAction = cmp.Or(c.General.CloseAction, defaults.General.CloseAction)
+
+	if c.UI == nil {
+		c.UI = defaults.UI
+	}
+	c.UI.Language = cmp.Or(c.UI.Language, defaults.UI.Language)
+	c.UI.Theme = cmp.Or(c.UI.Theme, defaults.UI.Theme)
+	c.UI.Width = cmp.Or(c.UI.Width, defaults.UI.Width)
+	c.UI.Height = cmp.Or(c.UI.Height, defaults.UI.Height)
+
+	if c.Log == nil {
+		c.Log = defaults.Log
+	}
+	c.Log.Level = cmp.Or(c.Log.Level, defaults.Log.Level)
+
+	if c.Record == nil {
+		c.Record = defaults.Record
+	}
+
+	if c.Admin == nil {
+		c.Admin = defaults.Admin
+	}
+	c.Admin.Listen = cmp.Or(c.Admin.Listen, defaults.Admin.Listen)
+
+	if c.Telemetry == nil {
+		c.Telemetry = defaults.Telemetry
+	}
+	c.Telemetry.OTLPEndpoint = cmp.Or(c.Telemetry.OTLPEndpoint, defaults.Telemetry.OTLPEndpoint)
+	c.Telemetry.ServiceName = cmp.Or(c.Telemetry.ServiceName, defaults.Telemetry.ServiceName)
+
+	if c.Outbound == nil {
+		c.Outbound = defaults.Outbound
+	}
+	c.Outbound.ConnectTimeout = cmp.Or(c.Outbound.ConnectTimeout, defaults.Outbound.ConnectTimeout)
+	c.Outbound.TLSTimeout = cmp.Or(c.Outbound.TLSTimeout, defaults.Outbound.TLSTimeout)
+	c.Outbound.ResponseHeaderTimeout = cmp.Or(c.Outbound.ResponseHeaderTimeout, defaults.Outbound.ResponseHeaderTimeout)
+	c.Outbound.StreamIdleTimeout = cmp.Or(c.Outbound.StreamIdleTimeout, defaults.Outbound.StreamIdleTimeout)
+	c.Outbound.MaxIdleConnsPerHost = cmp.Or(c.Outbound.MaxIdleConnsPerHost, defaults.Outbound.MaxIdleConnsPerHost)
+
+	if c.Proxy == nil {
+		c.Proxy = defaults.Proxy
+	}
+	c.Proxy.Mode = cmp.Or(c.Proxy.Mode, defaults.Proxy.Mode)
+
+	if c.Proxy.Gateway == nil {
+		c.Proxy.Gateway = defaults.Proxy.Gateway
+	}
+	c.Proxy.Gateway.Listen = cmp.Or(c.Proxy.Gateway.Listen, defaults.Proxy.Gateway.Listen)
+	c.Proxy.Gateway.GroupHeader = cmp.Or(c.Proxy.Gateway.GroupHeader, defaults.Proxy.Gateway.GroupHeader)
+
+	if c.Proxy.Resolver == nil {
+		c.Proxy.Resolver = defaults.Proxy.Resolver
+	}
+	if len(c.Proxy.Resolver.Servers) == 0 {
+		c.Proxy.Resolver.Servers = defaults.Proxy.Resolver.Servers
+	}
+
+	if c.Proxy.Host == nil {
+		c.Proxy.Host = defaults.Proxy.Host
+	}
+	c.Proxy.Host.Method = cmp.Or(c.Proxy.Host.Method, defaults.Proxy.Host.Method)
+	c.Proxy.Host.Listen = cmp.Or(c.Proxy.Host.Listen, defaults.Proxy.Host.Listen)
+	c.Proxy.Host.DNSListen = cmp.Or(c.Proxy.Host.DNSListen, defaults.Proxy.Host.DNSListen)
+
+	if c.Proxy.Cert == nil {
+		c.Proxy.Cert = defaults.Proxy.Cert
+	}
+	c.Proxy.Cert.KeyType = cmp.Or(c.Proxy.Cert.KeyType, defaults.Proxy.Cert.KeyType)
+	c.Proxy.Cert.CacheSize = cmp.Or(c.Proxy.Cert.CacheSize, defaults.Proxy.Cert.CacheSize)
+}
@@ -28,7 +28,7 @@ func (n *NilConverter) Name() string {
 }
 
 func (n *NilConverter) ConvertRequest(request *http.Request, channel channel.Channel) (result *http.Request, err error) {
-	result = &http.Request{}
+	result = (&http.Request{}).WithContext(request.Context())
 	// 1、处理url、path、header
 	var u *url.URL
 	if request.URL.Path == "/v1/messages" {
@@ -45,7 +45,7 @@ func (g *GeminiConverter) ConvertResponse(response *http.Response, channel chann
 func (g *GeminiConverter) ConvertStream(response *http.Response, channel channel.Channel) (*http.Response, error) {
 	body := response.Body
 	model := response.Request.Header.Get("original_model")
-	reader, writer := io.Pipe()
+	reader, writer := convert.StreamPipe(body)
 	response.Body = reader
 	response.ContentLength = -1
 	response.Header.Del("Content-Length")
@@ -98,6 +98,12 @@ func (g *GeminiConverter) ConvertStream(response *http.Response, channel channel
 				}
 			}
 		}
+		// 上游读取失败（客户端取消、空闲超时等）时将错误传递给客户端一侧，不再补发结束数据
+		if err := scanner.Err(); err != nil {
+			slog.Debug("读取上游流式响应失败", "error", err)
+			_ = writer.CloseWithError(err)
+			return
+		}
 		slog.Debug("stream 处理完成", "count", count)
 		// 如果没有处理任何chunks，发送一个错误响应
 		if count == 0 {
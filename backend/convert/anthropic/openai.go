@@ -3,6 +3,7 @@ package anthropic
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -93,7 +94,7 @@ func (o OpenAIConverter) prepareUrl(request *http.Request, channel channel.Chann
 		return nil, errorx.With(err, "url 解析失败")
 	}
 
-	result = &http.Request{}
+	result = (&http.Request{}).WithContext(request.Context())
 	result.URL = u
 	result.Host = u.Host
 	result.Body = request.Body
@@ -301,7 +302,7 @@ func (o OpenAIConverter) ConvertResponse(response *http.Response, channel channe
 	} else {
 		// 处理对话消息转换
 		slog.Debug(fmt.Sprintf("[%s] [%s] 开始处理对话消息转换", channel.Name, o.Name()))
-		body = o.convertMessages(body, model, channel.Name)
+		body = o.convertMessages(response.Request.Context(), body, model, channel.Name)
 		slog.Debug(fmt.Sprintf("[%s] [%s] 对话消息转换处理完成", channel.Name, o.Name()))
 	}
 
@@ -315,7 +316,7 @@ func (o OpenAIConverter) convertModels(body []byte, model string) []byte {
 	return body
 }
 
-func (o OpenAIConverter) convertMessages(body []byte, model, name string) []byte {
+func (o OpenAIConverter) convertMessages(ctx context.Context, body []byte, model, name string) []byte {
 	var data = gjson.ParseBytes(body)
 	var usage = convert.TokenUsage{}
 	var result = map[string]any{
@@ -434,7 +435,7 @@ func (o OpenAIConverter) convertMessages(body []byte, model, name string) []byte
 	}
 
 	// 统计
-	statistics.UpdateStatistics(name, true, usage.InputTokens, usage.OutputTokens)
+	statistics.UpdateStatisticsContext(ctx, name, true, usage.InputTokens, usage.OutputTokens)
 
 	// 序列化数据
 	bys, _ := json.Marshal(result)
@@ -443,7 +444,7 @@ func (o OpenAIConverter) convertMessages(body []byte, model, name string) []byte
 
 func (o OpenAIConverter) ConvertStream(response *http.Response, channel channel.Channel) (*http.Response, error) {
 	var body = response.Body
-	var reader, writer = io.Pipe()
+	reader, writer := convert.StreamPipe(body)
 	var model = response.Request.Header.Get("original_model")
 
 	response.Body = reader
@@ -653,10 +654,16 @@ func (o OpenAIConverter) ConvertStream(response *http.Response, channel channel.
 			for _, event := range events {
 				if _, err := writer.Write([]byte(event)); err != nil {
 					slog.Warn(fmt.Sprintf("[%s] 数据写入失败", o.Name()))
-					continue
+					return
 				}
 			}
 		}
+		// 上游读取失败（客户端取消、空闲超时等）时将错误传递给客户端一侧，不再补发结束数据
+		if err := scanner.Err(); err != nil {
+			slog.Debug("读取上游流式响应失败", "error", err)
+			_ = writer.CloseWithError(err)
+			return
+		}
 		slog.Debug(fmt.Sprintf("[%s] [%s] 流式响应处理完成", channel.Name, o.Name()))
 	}()
 
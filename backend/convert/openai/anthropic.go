@@ -44,7 +44,7 @@ func (a *AnthropicConverter) Name() string {
 
 // ConvertRequest 转换OpenAI请求到Anthropic格式
 func (a *AnthropicConverter) ConvertRequest(request *http.Request, channel channel.Channel) (result *http.Request, err error) {
-	result = &http.Request{}
+	result = (&http.Request{}).WithContext(request.Context())
 	// 1、处理url、path、header
 	var u *url.URL
 	if strings.HasSuffix(channel.URL, "/") {
@@ -357,10 +357,10 @@ func (a *AnthropicConverter) ConvertResponse(response *http.Response, channel ch
 	}
 
 	if body, err := json.Marshal(result); err != nil {
-		statistics.UpdateStatistics(channel.Name, false, tokenUsage.InputTokens, tokenUsage.OutputTokens)
+		statistics.UpdateStatisticsContext(response.Request.Context(), channel.Name, false, tokenUsage.InputTokens, tokenUsage.OutputTokens)
 		return response, err
 	} else {
-		statistics.UpdateStatistics(channel.Name, true, tokenUsage.InputTokens, tokenUsage.OutputTokens)
+		statistics.UpdateStatisticsContext(response.Request.Context(), channel.Name, true, tokenUsage.InputTokens, tokenUsage.OutputTokens)
 		response.Body = io.NopCloser(bytes.NewReader(body))
 		return response, nil
 	}
@@ -370,7 +370,7 @@ func (a *AnthropicConverter) ConvertResponse(response *http.Response, channel ch
 func (a *AnthropicConverter) ConvertStream(response *http.Response, channel channel.Channel) (*http.Response, error) {
 	body := response.Body
 	model := response.Request.Header.Get("original_model")
-	reader, writer := io.Pipe()
+	reader, writer := convert.StreamPipe(body)
 	response.Body = reader
 	response.ContentLength = -1
 	response.Header.Del("Content-Length")
@@ -488,6 +488,12 @@ func (a *AnthropicConverter) ConvertStream(response *http.Response, channel chan
 				}
 			}
 		}
+		// 上游读取失败（客户端取消、空闲超时等）时将错误传递给客户端一侧，不再补发结束数据
+		if err := scanner.Err(); err != nil {
+			slog.Debug("读取上游流式响应失败", "error", err)
+			_ = writer.CloseWithError(err)
+			return
+		}
 		slog.Debug("stream 处理完成", "count", count)
 		// 如果没有处理任何chunks，发送一个错误响应
 		if count == 0 {
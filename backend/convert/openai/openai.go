@@ -55,7 +55,7 @@ func (n NilConverter) ConvertResponse(response *http.Response, channel channel.C
 		OutputTokens: uint64(openaiResponse.Usage.CompletionTokens),
 	}
 
-	statistics.UpdateStatistics(channel.Name, true, usage.InputTokens, usage.OutputTokens)
+	statistics.UpdateStatisticsContext(response.Request.Context(), channel.Name, true, usage.InputTokens, usage.OutputTokens)
 
 	return response, nil
 }
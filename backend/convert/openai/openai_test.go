@@ -0,0 +1,40 @@
+package openai
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/statistics"
+)
+
+func TestConvertResponseSkipsExcludedStatistics(t *testing.T) {
+	stats := statistics.NewManager(t.TempDir())
+	t.Cleanup(func() { _ = stats.Close() })
+
+	respond := func(request *http.Request) {
+		t.Helper()
+		response := &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"usage":{"prompt_tokens":3,"completion_tokens":4}}`)),
+			Request:    request,
+		}
+		if _, err := (NilConverter{}).ConvertResponse(response, channel.Channel{Name: "test"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	respond(request.WithContext(statistics.Exclude(request.Context())))
+	if got := stats.GetAllStatistics()["test"]; got != nil {
+		t.Fatalf("replayed response counted: %+v", got)
+	}
+
+	respond(request)
+	if got := stats.GetAllStatistics()["test"]; got == nil || got.RequestCount != 1 || got.OutputToken != 4 {
+		t.Fatalf("stats = %+v", got)
+	}
+}
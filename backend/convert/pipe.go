@@ -0,0 +1,20 @@
+package convert
+
+import "io"
+
+// StreamPipe 创建流式响应的转换管道，转换协程从上游读取数据并写入返回的 writer。
+// 客户端关闭响应体时同时关闭上游响应体，阻塞在读取上游数据的转换协程会立即退出
+func StreamPipe(upstream io.ReadCloser) (io.ReadCloser, *io.PipeWriter) {
+	reader, writer := io.Pipe()
+	return &pipeBody{PipeReader: reader, upstream: upstream}, writer
+}
+
+type pipeBody struct {
+	*io.PipeReader
+	upstream io.ReadCloser
+}
+
+func (b *pipeBody) Close() error {
+	_ = b.upstream.Close()
+	return b.PipeReader.Close()
+}
@@ -1,72 +1,205 @@
 package host
 
 import (
-	"bufio"
+	"cmp"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 )
 
-type Manager struct{}
+const (
+	beginMarker  = "# BEGIN Chameleon" // 托管区块开始标记
+	endMarker    = "# END Chameleon"   // 托管区块结束标记
+	legacyRemark = "# Chameleon-"      // 旧版本逐行追加时使用的备注
+)
+
+// Manager hosts 文件管理器，劫持记录统一写在 BEGIN/END 标记之间的托管区块中
+type Manager struct {
+	path       string // hosts 文件路径
+	backupPath string // 修改前的备份文件路径
+	mu         sync.Mutex
+}
 
-type Entity struct {
-	ip     string // 固定为127.0.0.1
-	host   string // 需要被劫持的host
-	remark string // 备注：Chameleon-{host}
+// NewManager 创建 hosts 文件管理器，修改前会将原文件备份到 backupDir/hosts.bak
+func NewManager(hostsPath, backupDir string) *Manager {
+	return &Manager{
+		path:       hostsPath,
+		backupPath: filepath.Join(backupDir, "hosts.bak"),
+	}
 }
 
-// getHostsPath 获取系统hosts文件路径
-func getHostsPath() string {
+// SystemPath 获取系统hosts文件路径
+func SystemPath() string {
 	if runtime.GOOS == "windows" {
-		return "C:\\Windows\\System32\\drivers\\etc\\hosts"
+		return filepath.Join(cmp.Or(os.Getenv("SystemRoot"), `C:\Windows`), "System32", "drivers", "etc", "hosts")
 	}
 	return "/etc/hosts"
 }
 
-// AddHosts 添加host集合
+// AddHosts 将域名劫持到本机（127.0.0.1 和 ::1），替换已有的托管区块，重复调用结果相同
 func (m *Manager) AddHosts(hosts []string) error {
-	hostsPath := getHostsPath()
-	file, err := os.OpenFile(hostsPath, os.O_RDWR|os.O_APPEND, 0644)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.update(func(lines []string) []string {
+		return append(lines, block(hosts)...)
+	})
+}
+
+// RemoveHosts 移除托管区块
+func (m *Manager) RemoveHosts() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.update(func(lines []string) []string {
+		return lines
+	})
+}
+
+// Cleanup 启动时清理上次异常退出残留的劫持记录，返回是否存在残留
+func (m *Manager) Cleanup() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	content, err := os.ReadFile(m.path)
 	if err != nil {
-		return fmt.Errorf("打开hosts文件失败: %w", err)
+		return false, fmt.Errorf("读取hosts文件失败: %w", err)
+	}
+	if _, managed := parse(string(content)); !managed {
+		return false, nil
 	}
-	defer file.Close()
+	slog.Warn("发现上次未清理的 hosts 劫持记录，正在移除", "path", m.path)
+	return true, m.update(func(lines []string) []string {
+		return lines
+	})
+}
 
-	for _, host := range hosts {
-		entity := Entity{
-			ip:     "127.0.0.1",
-			host:   host,
-			remark: fmt.Sprintf("Chameleon-%s", host),
-		}
-		line := fmt.Sprintf("%s\t%s\t# %s\n", entity.ip, entity.host, entity.remark)
-		if _, err := file.WriteString(line); err != nil {
-			return fmt.Errorf("写入hosts失败: %w", err)
+// update 读取 hosts 文件并去除托管内容，经 fn 修改后写回；内容没有变化时不写文件
+func (m *Manager) update(fn func(lines []string) []string) error {
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return fmt.Errorf("读取hosts文件失败: %w", err)
+	}
+	content, err := os.ReadFile(m.path)
+	if err != nil {
+		return fmt.Errorf("读取hosts文件失败: %w", err)
+	}
+
+	original := string(content)
+	newline := "\n"
+	if strings.Contains(original, "\r\n") {
+		newline = "\r\n"
+	}
+	lines, managed := parse(original)
+	updated := fn(lines)
+	if !managed && len(updated) == len(lines) {
+		return nil
+	}
+	result := strings.Join(updated, newline)
+	if result != "" {
+		result += newline
+	}
+	if result == original {
+		return nil
+	}
+
+	// 备份保存不含劫持记录的原始文件，已有备份时不被带有托管区块的内容覆盖
+	if _, err := os.Stat(m.backupPath); !managed || err != nil {
+		if err := os.WriteFile(m.backupPath, content, 0644); err != nil {
+			return fmt.Errorf("备份hosts文件失败: %w", err)
 		}
 	}
+	if err := writeAtomic(m.path, []byte(result), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("写入hosts失败: %w", err)
+	}
 	return nil
 }
 
-// RemoveHosts 移除添加到系统host中的数据
-func (m *Manager) RemoveHosts() error {
-	hostsPath := getHostsPath()
-	file, err := os.Open(hostsPath)
-	if err != nil {
-		return fmt.Errorf("打开hosts文件失败: %w", err)
+// parse 拆分 hosts 文件内容，去除托管区块和旧版本逐行追加的记录，返回剩余的行以及是否存在托管内容
+func parse(content string) ([]string, bool) {
+	content = strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if content == "" {
+		return nil, false
 	}
-	defer file.Close()
 
 	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.Contains(line, "Chameleon-") {
+	managed, inBlock := false, false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == beginMarker:
+			managed, inBlock = true, true
+		case trimmed == endMarker:
+			managed, inBlock = true, false
+		case inBlock, strings.Contains(line, legacyRemark):
+			managed = true
+		default:
 			lines = append(lines, line)
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("读取hosts文件失败: %w", err)
+	return lines, managed
+}
+
+// block 生成托管区块，域名去重排序，保证相同输入生成相同内容
+func block(hosts []string) []string {
+	hosts = slices.DeleteFunc(slices.Clone(hosts), func(h string) bool { return strings.TrimSpace(h) == "" })
+	slices.Sort(hosts)
+	hosts = slices.Compact(hosts)
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	lines := []string{beginMarker}
+	for _, host := range hosts {
+		lines = append(lines, "127.0.0.1\t"+host, "::1\t"+host)
 	}
+	return append(lines, endMarker)
+}
 
-	return os.WriteFile(hostsPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+// rename 替换文件，测试中替换以模拟 hosts 无法被重命名替换的情况
+var rename = os.Rename
+
+// writeAtomic 先写入同目录的临时文件再重命名替换；hosts 以挂载等方式存在无法替换时直接覆盖写入
+func writeAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".hosts-*.tmp")
+	if err == nil {
+		name := tmp.Name()
+		_, err = tmp.Write(data)
+		if err == nil {
+			err = tmp.Sync()
+		}
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(name, perm)
+		}
+		if err == nil {
+			err = rename(name, path)
+		}
+		if err == nil {
+			return nil
+		}
+		_ = os.Remove(name)
+	}
+	slog.Debug("原子替换hosts文件失败，改为直接写入", "error", err)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
 }
@@ -0,0 +1,157 @@
+package host
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const managedBlock = "# BEGIN Chameleon\n127.0.0.1\tapi.openai.com\n::1\tapi.openai.com\n# END Chameleon\n"
+
+func newTestManager(t *testing.T, content string) (*Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(path, dir), path
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestAddHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		hosts   []string
+		want    string
+	}{
+		{
+			name:    "append block",
+			content: "127.0.0.1 localhost\n",
+			hosts:   []string{"api.openai.com"},
+			want:    "127.0.0.1 localhost\n" + managedBlock,
+		},
+		{
+			name:    "dedupe and sort",
+			content: "127.0.0.1 localhost\n",
+			hosts:   []string{"b.com", "", "a.com", "b.com"},
+			want:    "127.0.0.1 localhost\n# BEGIN Chameleon\n127.0.0.1\ta.com\n::1\ta.com\n127.0.0.1\tb.com\n::1\tb.com\n# END Chameleon\n",
+		},
+		{
+			name:    "replace stale block",
+			content: "127.0.0.1 localhost\n# BEGIN Chameleon\n127.0.0.1\told.com\n# END Chameleon\n10.0.0.1 nas\n",
+			hosts:   []string{"api.openai.com"},
+			want:    "127.0.0.1 localhost\n10.0.0.1 nas\n" + managedBlock,
+		},
+		{
+			name:    "remove legacy lines",
+			content: "127.0.0.1 localhost\n127.0.0.1 api.openai.com # Chameleon-api.openai.com\n",
+			hosts:   []string{"api.openai.com"},
+			want:    "127.0.0.1 localhost\n" + managedBlock,
+		},
+		{
+			name:    "preserve CRLF",
+			content: "127.0.0.1 localhost\r\n",
+			hosts:   []string{"api.openai.com"},
+			want:    "127.0.0.1 localhost\r\n" + strings.ReplaceAll(managedBlock, "\n", "\r\n"),
+		},
+		{
+			name:    "empty hosts removes block",
+			content: "127.0.0.1 localhost\n" + managedBlock,
+			hosts:   nil,
+			want:    "127.0.0.1 localhost\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr, path := newTestManager(t, tt.content)
+			for i := 0; i < 2; i++ {
+				if err := mgr.AddHosts(tt.hosts); err != nil {
+					t.Fatal(err)
+				}
+				if got := readFile(t, path); got != tt.want {
+					t.Errorf("AddHosts() call %d:\ngot  %q\nwant %q", i+1, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveHostsAndCleanup(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        string
+		wantCleanup bool
+	}{
+		{name: "managed block", content: "127.0.0.1 localhost\n" + managedBlock, want: "127.0.0.1 localhost\n", wantCleanup: true},
+		{name: "legacy lines", content: "127.0.0.1 a.com # Chameleon-a.com\r\n127.0.0.1 localhost\r\n", want: "127.0.0.1 localhost\r\n", wantCleanup: true},
+		{name: "unterminated block", content: "127.0.0.1 localhost\n# BEGIN Chameleon\n127.0.0.1\ta.com\n", want: "127.0.0.1 localhost\n", wantCleanup: true},
+		{name: "untouched", content: "127.0.0.1 localhost\n", want: "127.0.0.1 localhost\n", wantCleanup: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr, path := newTestManager(t, tt.content)
+			if err := mgr.RemoveHosts(); err != nil {
+				t.Fatal(err)
+			}
+			if got := readFile(t, path); got != tt.want {
+				t.Errorf("RemoveHosts() got %q, want %q", got, tt.want)
+			}
+
+			mgr, path = newTestManager(t, tt.content)
+			found, err := mgr.Cleanup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != tt.wantCleanup {
+				t.Errorf("Cleanup() = %v, want %v", found, tt.wantCleanup)
+			}
+			if got := readFile(t, path); got != tt.want {
+				t.Errorf("Cleanup() left %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupKeepsOriginal(t *testing.T) {
+	const original = "127.0.0.1 localhost\n"
+	mgr, _ := newTestManager(t, original)
+	if err := mgr.AddHosts([]string{"a.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.AddHosts([]string{"b.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, mgr.backupPath); got != original {
+		t.Errorf("backup overwritten with managed content: %q", got)
+	}
+}
+
+func TestWriteAtomicFallback(t *testing.T) {
+	rename = func(string, string) error { return errors.New("device or resource busy") }
+	defer func() { rename = os.Rename }()
+
+	mgr, path := newTestManager(t, "127.0.0.1 localhost\n")
+	if err := mgr.AddHosts([]string{"api.openai.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, path), "127.0.0.1 localhost\n"+managedBlock; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	tmp, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".hosts-*.tmp"))
+	if len(tmp) != 0 {
+		t.Errorf("temporary files left behind: %v", tmp)
+	}
+}
@@ -0,0 +1,21 @@
+package host
+
+// Redirect 本机端口重定向规则，将访问回环地址标准端口的连接转到实际监听端口
+type Redirect struct {
+	Address   string // 回环监听地址，如 127.0.0.1
+	HTTPSPort uint16 // 443 端口重定向到的端口，0 或 443 表示不重定向
+	DNSPort   uint16 // 53 端口重定向到的端口，0 或 53 表示不重定向
+	IPv6      bool   // 是否同时重定向 ::1
+}
+
+// rules 需要重定向的协议、原端口和目标端口
+func (r Redirect) rules() [][3]string {
+	var rules [][3]string
+	if r.HTTPSPort != 0 && r.HTTPSPort != 443 {
+		rules = append(rules, [3]string{"tcp", "443", itoa(r.HTTPSPort)})
+	}
+	if r.DNSPort != 0 && r.DNSPort != 53 {
+		rules = append(rules, [3]string{"udp", "53", itoa(r.DNSPort)})
+	}
+	return rules
+}
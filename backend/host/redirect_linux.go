@@ -0,0 +1,110 @@
+package host
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// nftTable 规则所在的 nftables 表，停止时整表删除
+const nftTable = "chameleon"
+
+var (
+	iptablesRules [][]string // 已添加的 iptables 规则，移除时逐条删除
+	redirectMu    sync.Mutex
+)
+
+// AddRedirect 添加端口重定向规则，优先使用 nftables，不可用时使用 iptables，需要 root 权限
+func AddRedirect(r Redirect) error {
+	redirectMu.Lock()
+	defer redirectMu.Unlock()
+
+	rules := r.rules()
+	if len(rules) == 0 {
+		return nil
+	}
+	removeRedirect()
+
+	if _, err := exec.LookPath("nft"); err == nil {
+		var script strings.Builder
+		fmt.Fprintf(&script, "table inet %s {\n\tchain output {\n\t\ttype nat hook output priority -100; policy accept;\n", nftTable)
+		for _, rule := range rules {
+			fmt.Fprintf(&script, "\t\tip daddr %s %s dport %s redirect to :%s\n", r.Address, rule[0], rule[1], rule[2])
+			if r.IPv6 {
+				fmt.Fprintf(&script, "\t\tip6 daddr ::1 %s dport %s redirect to :%s\n", rule[0], rule[1], rule[2])
+			}
+		}
+		script.WriteString("\t}\n}\n")
+		cmd := exec.Command("nft", "-f", "-")
+		cmd.Stdin = strings.NewReader(script.String())
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("添加 nftables 规则失败: %s", bytes.TrimSpace(out))
+		}
+		slog.Info("已添加 nftables 端口重定向规则", "table", nftTable)
+		return nil
+	}
+
+	if _, err := exec.LookPath("iptables"); err != nil {
+		return fmt.Errorf("未找到 nft 或 iptables 命令")
+	}
+	for _, rule := range rules {
+		targets := [][2]string{{"iptables", r.Address}}
+		if r.IPv6 {
+			targets = append(targets, [2]string{"ip6tables", "::1"})
+		}
+		for _, target := range targets {
+			args := []string{target[0], "-t", "nat", "OUTPUT", "-p", rule[0], "-d", target[1], "--dport", rule[1],
+				"-m", "comment", "--comment", nftTable, "-j", "REDIRECT", "--to-ports", rule[2]}
+			if err := iptables("-A", args); err != nil {
+				if target[0] == "ip6tables" {
+					slog.Warn("添加 IPv6 端口重定向规则失败", "error", err)
+					continue
+				}
+				removeRedirect()
+				return err
+			}
+			iptablesRules = append(iptablesRules, args)
+		}
+	}
+	slog.Info("已添加 iptables 端口重定向规则", "count", len(iptablesRules))
+	return nil
+}
+
+// RemoveRedirect 移除添加的端口重定向规则
+func RemoveRedirect() error {
+	redirectMu.Lock()
+	defer redirectMu.Unlock()
+	return removeRedirect()
+}
+
+func removeRedirect() error {
+	var err error
+	if _, lookErr := exec.LookPath("nft"); lookErr == nil {
+		// 表不存在时删除会失败，忽略
+		_ = exec.Command("nft", "delete", "table", "inet", nftTable).Run()
+	}
+	for _, args := range iptablesRules {
+		if e := iptables("-D", args); e != nil {
+			err = e
+		}
+	}
+	iptablesRules = nil
+	return err
+}
+
+// iptables 执行 iptables/ip6tables 命令，args[0] 为命令名，action 插入在链名之前
+func iptables(action string, args []string) error {
+	cmdArgs := append([]string{args[1], args[2], action}, args[3:]...)
+	if out, err := exec.Command(args[0], cmdArgs...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s 失败: %s", args[0], action, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func itoa(port uint16) string {
+	return strconv.Itoa(int(port))
+}
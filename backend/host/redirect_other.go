@@ -0,0 +1,25 @@
+//go:build !linux
+
+package host
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// AddRedirect 端口重定向仅支持 Linux
+func AddRedirect(r Redirect) error {
+	if len(r.rules()) == 0 {
+		return nil
+	}
+	return fmt.Errorf("端口重定向仅支持 Linux")
+}
+
+// RemoveRedirect 端口重定向仅支持 Linux
+func RemoveRedirect() error {
+	return nil
+}
+
+func itoa(port uint16) string {
+	return strconv.Itoa(int(port))
+}
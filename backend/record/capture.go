@@ -0,0 +1,197 @@
+package record
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// redactedHeaders 需要脱敏的请求头
+var redactedHeaders = []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key", "Proxy-Authorization", "Cookie"}
+
+// redactedQueries 需要脱敏的查询参数
+var redactedQueries = []string{"key", "api_key"}
+
+// Message 捕获的单个请求或响应
+type Message struct {
+	Method    string              `json:"method,omitempty"` // 请求方法
+	URL       string              `json:"url,omitempty"`    // 请求地址
+	Status    int                 `json:"status,omitempty"` // 响应状态码
+	Header    map[string][]string `json:"header"`           // 头信息（已脱敏）
+	Body      string              `json:"body"`             // 内容
+	Truncated bool                `json:"truncated"`        // 内容是否因超出大小限制被截断
+}
+
+// Capture 单次请求的完整内容，用于排查转换问题
+type Capture struct {
+	ID               uint64   `json:"id"`                // 对应的请求记录ID
+	ClientRequest    *Message `json:"client_request"`    // 客户端原始请求
+	UpstreamRequest  *Message `json:"upstream_request"`  // 转换后发往上游的请求
+	UpstreamResponse *Message `json:"upstream_response"` // 上游原始响应
+	ClientResponse   *Message `json:"client_response"`   // 转换后返回客户端的响应
+}
+
+// capture 请求过程中收集的内容
+type capture struct {
+	maxBody          int
+	clientRequest    *Message
+	upstreamRequest  *Message
+	upstreamResponse *Message
+	upstreamBody     *limitBuffer
+	clientResponse   *Message
+	clientBody       *limitBuffer
+}
+
+// build 生成可保存的捕获内容
+func (c *capture) build() *Capture {
+	result := &Capture{
+		ClientRequest:    c.clientRequest,
+		UpstreamRequest:  c.upstreamRequest,
+		UpstreamResponse: c.upstreamResponse,
+		ClientResponse:   c.clientResponse,
+	}
+	if c.upstreamResponse != nil && c.upstreamBody != nil {
+		c.upstreamResponse.Body, c.upstreamResponse.Truncated = c.upstreamBody.snapshot()
+	}
+	if c.clientResponse != nil && c.clientBody != nil {
+		c.clientResponse.Body, c.clientResponse.Truncated = c.clientBody.snapshot()
+	}
+	return result
+}
+
+// CaptureClientRequest 捕获客户端原始请求，在转换请求前调用
+func (t *Trace) CaptureClientRequest(request *http.Request) {
+	if t == nil || t.capture == nil {
+		return
+	}
+	t.capture.clientRequest = t.captureRequest(request)
+}
+
+// CaptureUpstreamRequest 捕获转换后的上游请求，在转换请求后调用
+func (t *Trace) CaptureUpstreamRequest(request *http.Request) {
+	if t == nil || t.capture == nil {
+		return
+	}
+	t.capture.upstreamRequest = t.captureRequest(request)
+}
+
+// CaptureUpstreamResponse 捕获上游原始响应，在转换响应前调用
+func (t *Trace) CaptureUpstreamResponse(response *http.Response) {
+	if t == nil || t.capture == nil || response == nil {
+		return
+	}
+	t.capture.upstreamResponse = &Message{Status: response.StatusCode, Header: redactHeader(response.Header)}
+	if response.Body != nil {
+		t.capture.upstreamBody = &limitBuffer{max: t.capture.maxBody}
+		response.Body = &teeBody{ReadCloser: response.Body, buffer: t.capture.upstreamBody}
+	}
+}
+
+// captureRequest 读取请求内容并恢复请求体
+func (t *Trace) captureRequest(request *http.Request) *Message {
+	if request == nil {
+		return nil
+	}
+	message := &Message{Method: request.Method, Header: redactHeader(request.Header)}
+	if request.URL != nil {
+		message.URL = redactURL(request.URL)
+	}
+	if request.Body != nil && request.Body != http.NoBody {
+		body, _ := io.ReadAll(request.Body)
+		_ = request.Body.Close()
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		buffer := &limitBuffer{max: t.capture.maxBody}
+		_, _ = buffer.Write(body)
+		message.Body, message.Truncated = buffer.snapshot()
+	}
+	return message
+}
+
+// redactHeader 复制头信息并隐藏密钥
+func redactHeader(header http.Header) map[string][]string {
+	result := make(map[string][]string, len(header))
+	for key, values := range header {
+		result[key] = append([]string(nil), values...)
+	}
+	for _, key := range redactedHeaders {
+		key = http.CanonicalHeaderKey(key)
+		for i, value := range result[key] {
+			result[key][i] = mask(value)
+		}
+	}
+	return result
+}
+
+// redactURL 隐藏地址中的密钥参数
+func redactURL(u *url.URL) string {
+	copied := *u
+	query := copied.Query()
+	changed := false
+	for _, key := range redactedQueries {
+		if value := query.Get(key); value != "" {
+			query.Set(key, mask(value))
+			changed = true
+		}
+	}
+	if changed {
+		copied.RawQuery = query.Encode()
+	}
+	copied.User = nil
+	return copied.String()
+}
+
+// mask 仅保留密钥首尾少量字符
+func mask(value string) string {
+	prefix := ""
+	if strings.HasPrefix(value, "Bearer ") {
+		prefix, value = "Bearer ", strings.TrimPrefix(value, "Bearer ")
+	}
+	if len(value) <= 8 {
+		return prefix + "****"
+	}
+	return prefix + value[:4] + "****" + value[len(value)-4:]
+}
+
+// limitBuffer 并发安全且有大小上限的缓冲区
+type limitBuffer struct {
+	mu        sync.Mutex
+	buffer    bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if remain := b.max - b.buffer.Len(); b.max > 0 && len(p) > remain {
+		b.buffer.Write(p[:max(remain, 0)])
+		b.truncated = true
+		return len(p), nil
+	}
+	b.buffer.Write(p)
+	return len(p), nil
+}
+
+// snapshot 获取当前缓冲内容
+func (b *limitBuffer) snapshot() (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.ToValidUTF8(b.buffer.String(), "�"), b.truncated
+}
+
+// teeBody 读取响应体的同时写入缓冲区
+type teeBody struct {
+	io.ReadCloser
+	buffer *limitBuffer
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = b.buffer.Write(p[:n])
+	}
+	return n, err
+}
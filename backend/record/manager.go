@@ -0,0 +1,382 @@
+package record
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/config"
+	bolt "go.etcd.io/bbolt"
+)
+
+// cleanupInterval 过期记录清理间隔
+const cleanupInterval = time.Hour
+
+var (
+	bucketRecords  = []byte("records")
+	bucketCaptures = []byte("captures")
+)
+
+// Manager 请求记录管理器
+type Manager struct {
+	path      string
+	db        *bolt.DB
+	configMgr *config.Manager
+	captured  int // 捕获内容占用的存储大小
+	stop      chan struct{}
+	done      chan struct{}
+	mu        sync.RWMutex
+}
+
+// NewManager 创建请求记录管理器
+func NewManager(dataDir string, configMgr *config.Manager) *Manager {
+	m := &Manager{
+		path:      filepath.Join(dataDir, "records.db"),
+		configMgr: configMgr,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	if err := m.open(); err != nil {
+		slog.Error("打开请求记录数据库失败", "path", m.path, "error", err)
+		close(m.done)
+		return m
+	}
+
+	go m.cleanupLoop()
+	return m
+}
+
+// open 打开数据库并创建bucket
+func (m *Manager) open() error {
+	db, err := bolt.Open(m.path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketRecords, bucketCaptures} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		// 统计已有捕获内容的大小
+		return tx.Bucket(bucketCaptures).ForEach(func(_, v []byte) error {
+			m.captured += len(v)
+			return nil
+		})
+	}); err != nil {
+		_ = db.Close()
+		return err
+	}
+	m.db = db
+	return nil
+}
+
+// config 获取请求记录配置
+func (m *Manager) config() *config.RecordConfig {
+	if m.configMgr == nil || m.configMgr.GetConfig().Record == nil {
+		return &config.RecordConfig{}
+	}
+	return m.configMgr.GetConfig().Record
+}
+
+// Enabled 是否启用请求记录
+func (m *Manager) Enabled() bool {
+	return m.db != nil && m.config().Enabled
+}
+
+// Add 保存一条请求记录，记录ID由数据库分配
+func (m *Manager) Add(r *Record) error {
+	return m.save(r, nil)
+}
+
+// save 保存请求记录及其捕获内容
+func (m *Manager) save(r *Record, c *Capture) error {
+	if !m.Enabled() {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var captured int
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketRecords)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		r.ID = id
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(itob(id), data); err != nil {
+			return err
+		}
+
+		if c == nil {
+			return nil
+		}
+		c.ID = id
+		if data, err = json.Marshal(c); err != nil {
+			return err
+		}
+		captured = len(data)
+		return tx.Bucket(bucketCaptures).Put(itob(id), data)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.captured += captured
+	if limit := m.config().CaptureMaxMB << 20; limit > 0 && m.captured > limit {
+		return m.trimCaptures(limit)
+	}
+	return nil
+}
+
+// trimCaptures 从最旧的捕获内容开始删除，直到总大小不超过上限
+func (m *Manager) trimCaptures(limit int) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketCaptures)
+		var keys [][]byte
+		size := m.captured
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil && size > limit; k, v = cursor.Next() {
+			keys = append(keys, k)
+			size -= len(v)
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		m.captured = size
+		return nil
+	})
+}
+
+// GetCapture 获取请求的完整捕获内容，用于对比转换前后的数据
+func (m *Manager) GetCapture(id uint64) (*Capture, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("请求记录数据库未打开")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var c *Capture
+	err := m.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketCaptures).Get(itob(id))
+		if data == nil {
+			return fmt.Errorf("请求内容未捕获: %d", id)
+		}
+		c = &Capture{}
+		return json.Unmarshal(data, c)
+	})
+	return c, err
+}
+
+// GetRecord 获取单条请求记录
+func (m *Manager) GetRecord(id uint64) (*Record, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("请求记录数据库未打开")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var r *Record
+	err := m.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketRecords).Get(itob(id))
+		if data == nil {
+			return fmt.Errorf("请求记录不存在: %d", id)
+		}
+		r = &Record{}
+		return json.Unmarshal(data, r)
+	})
+	return r, err
+}
+
+// QueryRecords 按条件分页查询请求记录，结果按时间倒序
+func (m *Manager) QueryRecords(filter Filter) (*Page, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("请求记录数据库未打开")
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	page := &Page{Page: filter.Page, PageSize: filter.PageSize, Records: []*Record{}}
+	offset := (filter.Page - 1) * filter.PageSize
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	err := m.db.View(func(tx *bolt.Tx) error {
+		// 记录在请求结束时写入，写入顺序与请求开始时间不一致，需遍历全部记录
+		cursor := tx.Bucket(bucketRecords).Cursor()
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			var r Record
+			if err := json.Unmarshal(v, &r); err != nil {
+				slog.Warn("跳过损坏的请求记录", "id", btoi(k), "error", err)
+				continue
+			}
+			if !filter.match(&r) {
+				continue
+			}
+			if page.Total >= offset && len(page.Records) < filter.PageSize {
+				page.Records = append(page.Records, &r)
+			}
+			page.Total++
+		}
+		return nil
+	})
+	return page, err
+}
+
+// ClearRecords 清空所有请求记录
+func (m *Manager) ClearRecords() error {
+	if m.db == nil {
+		return fmt.Errorf("请求记录数据库未打开")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketRecords, bucketCaptures} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		m.captured = 0
+		slog.Info("请求记录已清空")
+	}
+	return err
+}
+
+// Cleanup 按保留策略清理过期及超量的请求记录
+func (m *Manager) Cleanup() error {
+	if m.db == nil {
+		return nil
+	}
+
+	cfg := m.config()
+	var before int64
+	if cfg.RetentionDays > 0 {
+		before = time.Now().AddDate(0, 0, -cfg.RetentionDays).UnixMilli()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var removed int
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketRecords)
+		excess := 0
+		if cfg.MaxRecords > 0 {
+			excess = bucket.Stats().KeyN - cfg.MaxRecords
+		}
+
+		// 从最旧的记录开始收集，遍历时删除会导致游标跳过元素
+		var keys [][]byte
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			if excess <= 0 {
+				if before == 0 {
+					break
+				}
+				var r Record
+				if err := json.Unmarshal(v, &r); err == nil && r.Time >= before {
+					break
+				}
+			}
+			keys = append(keys, k)
+			excess--
+		}
+		captures := tx.Bucket(bucketCaptures)
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+			if v := captures.Get(k); v != nil {
+				m.captured -= len(v)
+				if err := captures.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		removed = len(keys)
+		return nil
+	})
+	if removed > 0 {
+		slog.Info("已清理过期请求记录", "count", removed)
+	}
+	return err
+}
+
+// Close 关闭请求记录数据库
+func (m *Manager) Close() error {
+	if m.db == nil {
+		return nil
+	}
+
+	select {
+	case <-m.stop:
+		return nil
+	default:
+		close(m.stop)
+	}
+	<-m.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.db.Close()
+}
+
+// cleanupLoop 定期清理过期记录
+func (m *Manager) cleanupLoop() {
+	defer close(m.done)
+	if err := m.Cleanup(); err != nil {
+		slog.Warn("清理请求记录失败", "error", err)
+	}
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Cleanup(); err != nil {
+				slog.Warn("清理请求记录失败", "error", err)
+			}
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func btoi(b []byte) uint64 {
+	return binary.BigEndian.Uint64(b)
+}
@@ -0,0 +1,51 @@
+package record
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/config"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	dir := t.TempDir()
+	configMgr, err := config.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(dir, configMgr)
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+// 先开始的长请求后结束时写在后面，不能因此漏掉时间范围内先结束的请求
+func TestQueryRecordsOutOfOrder(t *testing.T) {
+	m := newTestManager(t)
+	now := time.Now().UnixMilli()
+	for _, r := range []*Record{
+		{Group: "api.openai.com", Time: now - 2000}, // 后开始、先结束
+		{Group: "api.openai.com", Time: now - 3000}, // 先开始、后结束
+		{Group: "api.openai.com", Time: now - 1000},
+	} {
+		if err := m.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := m.QueryRecords(Filter{Start: now - 2500, End: now - 1500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || len(page.Records) != 1 || page.Records[0].Time != now-2000 {
+		t.Fatalf("QueryRecords() = %d 条 %+v, want 开始于 now-2000", page.Total, page.Records)
+	}
+
+	page, err = m.QueryRecords(Filter{Start: now - 2500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 {
+		t.Errorf("Total = %d, want 2", page.Total)
+	}
+}
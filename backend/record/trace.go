@@ -0,0 +1,346 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/tidwall/gjson"
+)
+
+// maxCaptureSize 解析用量或错误信息时最多缓存的响应体大小
+const maxCaptureSize = 4 << 20
+
+// maxErrorLength 错误信息最大长度
+const maxErrorLength = 1024
+
+// StatusClientClosed 客户端在响应结束前断开连接时记录的状态码
+const StatusClientClosed = 499
+
+// ErrAborted 客户端在响应结束前断开连接
+var ErrAborted = errors.New("客户端取消请求")
+
+// usagePaths 各供应商格式中的token用量字段，[输入, 输出]
+var usagePaths = [][2]string{
+	{"usage.input_tokens", "usage.output_tokens"},                            // anthropic
+	{"message.usage.input_tokens", "message.usage.output_tokens"},            // anthropic message_start
+	{"usage.prompt_tokens", "usage.completion_tokens"},                       // openai
+	{"usageMetadata.promptTokenCount", "usageMetadata.candidatesTokenCount"}, // gemini
+}
+
+// Trace 跟踪单次代理请求，在响应结束时写入请求记录
+type Trace struct {
+	mgr     *Manager
+	channel *channel.Channel
+	start   time.Time
+	record  Record
+	capture *capture // 完整内容捕获，未开启时为nil
+	detach  bool     // 不保存到数据库
+	hooks   []func(*Record)
+	once    sync.Once
+}
+
+// Begin 开始跟踪一次请求
+func (m *Manager) Begin(group string, ch *channel.Channel, originalModel string) *Trace {
+	t := &Trace{
+		mgr:     m,
+		channel: ch,
+		start:   time.Now(),
+		record: Record{
+			Group:         group,
+			OriginalModel: originalModel,
+		},
+	}
+	t.record.Time = t.start.UnixMilli()
+	if cfg := m.config(); m.Enabled() && cfg.Capture {
+		t.capture = &capture{maxBody: cfg.CaptureBodyKB << 10}
+	}
+	if ch != nil {
+		t.record.Channel = ch.Name
+		t.record.Converter = ch.ConverterName
+		t.record.MappedModel = originalModel
+		if ch.ModelMapper != nil && originalModel != "" {
+			t.record.MappedModel = ch.ModelMapper.MapModel(originalModel)
+		}
+	}
+	return t
+}
+
+// Detached 创建不保存到数据库且始终捕获完整内容的跟踪，用于重放等调试场景
+func (m *Manager) Detached(group string, ch *channel.Channel, originalModel string) *Trace {
+	t := m.Begin(group, ch, originalModel)
+	t.detach = true
+	if t.capture == nil {
+		t.capture = &capture{maxBody: max(m.config().CaptureBodyKB, 1024) << 10}
+	}
+	return t
+}
+
+// OnFinish 注册请求结束时的回调，用于指标统计、链路追踪等
+func (t *Trace) OnFinish(hook func(*Record)) {
+	if t == nil {
+		return
+	}
+	t.hooks = append(t.hooks, hook)
+}
+
+// SetKey 设置发起请求的网关客户端密钥
+func (t *Trace) SetKey(name string) {
+	if t == nil {
+		return
+	}
+	t.record.Key = name
+}
+
+// Result 获取请求记录和捕获内容，需在响应结束后调用
+func (t *Trace) Result() (*Record, *Capture) {
+	r := t.record
+	var c *Capture
+	if t.capture != nil {
+		c = t.capture.build()
+	}
+	return &r, c
+}
+
+// RequestModel 读取请求体中的模型名称，并恢复请求体
+func RequestModel(request *http.Request) string {
+	if request.Body == nil || request.Method == http.MethodGet {
+		return ""
+	}
+	body, err := io.ReadAll(request.Body)
+	_ = request.Body.Close()
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	return gjson.GetBytes(body, "model").String()
+}
+
+// Fail 以错误结束跟踪
+func (t *Trace) Fail(status int, err error) {
+	if t == nil {
+		return
+	}
+	t.finish(status, err)
+}
+
+// Wrap 包装响应体，在响应体读取完毕或关闭时结束跟踪
+func (t *Trace) Wrap(response *http.Response) *http.Response {
+	if t == nil || response == nil {
+		return response
+	}
+	if response.Body == nil {
+		t.finish(response.StatusCode, nil)
+		return response
+	}
+
+	t.record.Stream = strings.Contains(response.Header.Get("Content-Type"), "text/event-stream")
+	body := &traceBody{ReadCloser: response.Body, trace: t, status: response.StatusCode, ctx: context.Background()}
+	if response.Request != nil {
+		body.ctx = response.Request.Context()
+	}
+	if t.capture != nil {
+		t.capture.clientResponse = &Message{Status: response.StatusCode, Header: redactHeader(response.Header)}
+		t.capture.clientBody = &limitBuffer{max: t.capture.maxBody}
+		body.capture = t.capture.clientBody
+	}
+	response.Body = body
+	return response
+}
+
+// finish 计算耗时和费用并保存记录，多次调用只生效一次
+func (t *Trace) finish(status int, err error) {
+	t.once.Do(func() {
+		r := &t.record
+		if errors.Is(err, ErrAborted) || errors.Is(err, context.Canceled) {
+			r.Aborted = true
+			status, err = StatusClientClosed, ErrAborted
+		}
+		r.Status = status
+		r.Latency = time.Since(t.start).Milliseconds()
+		r.Success = err == nil && status == http.StatusOK
+		if err != nil {
+			r.Error = truncate(err.Error())
+		}
+		if t.channel != nil {
+			r.Cost = (float64(r.InputTokens)*t.channel.InputPrice + float64(r.OutputTokens)*t.channel.OutputPrice) / 1e6
+		}
+		for _, hook := range t.hooks {
+			hook(r)
+		}
+		if t.detach {
+			return
+		}
+		var c *Capture
+		if t.capture != nil {
+			c = t.capture.build()
+		}
+		if err := t.mgr.save(r, c); err != nil {
+			slog.Warn("保存请求记录失败", "error", err)
+		}
+	})
+}
+
+// observe 解析响应数据中的token用量，取各字段出现过的最大值
+func (t *Trace) observe(data []byte) {
+	result := gjson.ParseBytes(data)
+	for _, paths := range usagePaths {
+		if v := result.Get(paths[0]).Uint(); v > t.record.InputTokens {
+			t.record.InputTokens = v
+		}
+		if v := result.Get(paths[1]).Uint(); v > t.record.OutputTokens {
+			t.record.OutputTokens = v
+		}
+	}
+}
+
+// traceBody 响应体包装器，记录首字时间并解析token用量
+type traceBody struct {
+	io.ReadCloser
+	trace   *Trace
+	ctx     context.Context // 请求的 context，被取消说明客户端已经断开
+	capture *limitBuffer    // 捕获返回客户端的内容
+	status  int
+	first   bool
+	done    bool
+	line    []byte       // 流式响应中未完整的行
+	buffer  bytes.Buffer // 非流式响应缓存
+}
+
+func (b *traceBody) Read(p []byte) (int, error) {
+	n, err := b.read(p)
+	b.end(err)
+	return n, err
+}
+
+// WriteTo 将响应体写入客户端，io.Copy 优先使用该方法，写入失败说明客户端已经断开
+func (b *traceBody) WriteTo(w io.Writer) (int64, error) {
+	buffer := make([]byte, 32<<10)
+	var written int64
+	for {
+		n, err := b.read(buffer)
+		if n > 0 {
+			m, werr := w.Write(buffer[:n])
+			written += int64(m)
+			if werr != nil {
+				b.complete(ErrAborted)
+				return written, werr
+			}
+		}
+		b.end(err)
+		if errors.Is(err, io.EOF) {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// read 读取响应体，记录首字时间并解析读取到的数据
+func (b *traceBody) read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if !b.first {
+			b.first = true
+			b.trace.record.TTFT = time.Since(b.trace.start).Milliseconds()
+		}
+		b.feed(p[:n])
+	}
+	return n, err
+}
+
+// end 响应体读取完毕或读取出错时结束跟踪
+func (b *traceBody) end(err error) {
+	if errors.Is(err, io.EOF) {
+		b.complete(nil)
+	} else if err != nil {
+		b.complete(err)
+	}
+}
+
+// Close 响应体未读取完毕就被关闭时，请求已被取消才记为客户端断开，
+// 否则按响应状态结束（如按 Content-Length 写完响应后不再读取 EOF）
+func (b *traceBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.ctx.Err() != nil {
+		b.complete(ErrAborted)
+	} else {
+		b.complete(nil)
+	}
+	return err
+}
+
+// feed 处理读取到的响应数据
+func (b *traceBody) feed(p []byte) {
+	if b.capture != nil {
+		_, _ = b.capture.Write(p)
+	}
+	if !b.trace.record.Stream {
+		if b.buffer.Len() < maxCaptureSize {
+			b.buffer.Write(p)
+		}
+		return
+	}
+
+	b.line = append(b.line, p...)
+	for {
+		i := bytes.IndexByte(b.line, '\n')
+		if i < 0 {
+			break
+		}
+		b.observeLine(b.line[:i])
+		b.line = b.line[i+1:]
+	}
+	if len(b.line) > maxCaptureSize {
+		b.line = b.line[:0]
+	}
+}
+
+// observeLine 解析SSE中的data行
+func (b *traceBody) observeLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return
+	}
+	data := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	if len(data) > 0 && data[0] == '{' {
+		b.trace.observe(data)
+	}
+}
+
+// complete 响应结束，生成请求记录
+func (b *traceBody) complete(err error) {
+	if b.done {
+		return
+	}
+	b.done = true
+	if len(b.line) > 0 {
+		b.observeLine(b.line)
+		b.line = nil
+	}
+	if b.buffer.Len() > 0 {
+		b.trace.observe(b.buffer.Bytes())
+	}
+	if err == nil && b.status != http.StatusOK {
+		err = errors.New(strings.TrimSpace(b.buffer.String()))
+		if b.trace.record.Stream || b.buffer.Len() == 0 {
+			err = errors.New(http.StatusText(b.status))
+		}
+	}
+	b.trace.finish(b.status, err)
+}
+
+func truncate(s string) string {
+	if len(s) <= maxErrorLength {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxErrorLength], "") + "..."
+}
@@ -0,0 +1,106 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// failWriter 模拟客户端断开后写入失败
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func newResponse(ctx context.Context, status int, body string) *http.Response {
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/chat/completions", nil)
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    request,
+	}
+}
+
+func TestTraceAborted(t *testing.T) {
+	const body = `{"usage":{"prompt_tokens":3,"completion_tokens":5}}`
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		status      int
+		consume     func(io.ReadCloser)
+		wantAborted bool
+		wantSuccess bool
+		wantStatus  int
+	}{
+		{
+			name:        "读取完毕",
+			ctx:         context.Background(),
+			status:      http.StatusOK,
+			consume:     func(b io.ReadCloser) { _, _ = io.Copy(io.Discard, b); _ = b.Close() },
+			wantSuccess: true,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:   "按长度写完后关闭",
+			ctx:    context.Background(),
+			status: http.StatusOK,
+			consume: func(b io.ReadCloser) {
+				_, _ = io.CopyN(io.Discard, b, int64(len(body)))
+				_ = b.Close()
+			},
+			wantSuccess: true,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "未读取的错误响应",
+			ctx:        context.Background(),
+			status:     http.StatusTooManyRequests,
+			consume:    func(b io.ReadCloser) { _ = b.Close() },
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:        "请求已取消",
+			ctx:         cancelled,
+			status:      http.StatusOK,
+			consume:     func(b io.ReadCloser) { _ = b.Close() },
+			wantAborted: true,
+			wantStatus:  StatusClientClosed,
+		},
+		{
+			name:        "写入客户端失败",
+			ctx:         context.Background(),
+			status:      http.StatusOK,
+			consume:     func(b io.ReadCloser) { _, _ = io.Copy(failWriter{}, b); _ = b.Close() },
+			wantAborted: true,
+			wantStatus:  StatusClientClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := (&Manager{}).Begin("api.openai.com", nil, "gpt-4o")
+			var finished []*Record
+			trace.OnFinish(func(r *Record) { finished = append(finished, r) })
+
+			response := trace.Wrap(newResponse(tt.ctx, tt.status, body))
+			tt.consume(response.Body)
+
+			if len(finished) != 1 {
+				t.Fatalf("结束回调调用 %d 次, want 1", len(finished))
+			}
+			r := finished[0]
+			if r.Aborted != tt.wantAborted || r.Success != tt.wantSuccess || r.Status != tt.wantStatus {
+				t.Errorf("Aborted = %v, Success = %v, Status = %d, want %v, %v, %d",
+					r.Aborted, r.Success, r.Status, tt.wantAborted, tt.wantSuccess, tt.wantStatus)
+			}
+			if tt.wantSuccess && (r.InputTokens != 3 || r.OutputTokens != 5) {
+				t.Errorf("tokens = %d/%d, want 3/5", r.InputTokens, r.OutputTokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package record
+
+// Record 单次代理请求记录
+type Record struct {
+	ID            uint64  `json:"id"`                // 记录ID，按时间递增
+	Time          int64   `json:"time"`              // 请求开始时间（毫秒时间戳）
+	Group         string  `json:"group"`             // 渠道组端点
+	Channel       string  `json:"channel"`           // 渠道名称
+	Converter     string  `json:"converter"`         // 转换器名称
+	Key           string  `json:"key,omitempty"`     // 网关客户端密钥名称
+	OriginalModel string  `json:"original_model"`    // 客户端请求的模型
+	MappedModel   string  `json:"mapped_model"`      // 映射后实际请求的模型
+	Stream        bool    `json:"stream"`            // 是否为流式响应
+	Status        int     `json:"status"`            // HTTP状态码
+	Success       bool    `json:"success"`           // 是否成功
+	Aborted       bool    `json:"aborted,omitempty"` // 客户端是否中途取消
+	Latency       int64   `json:"latency"`           // 总耗时（毫秒）
+	TTFT          int64   `json:"ttft"`              // 首字耗时（毫秒）
+	InputTokens   uint64  `json:"input_tokens"`      // 输入token数
+	OutputTokens  uint64  `json:"output_tokens"`     // 输出token数
+	Cost          float64 `json:"cost"`              // 费用（按渠道单价计算）
+	Error         string  `json:"error,omitempty"`   // 错误信息
+}
+
+// Filter 请求记录查询条件
+type Filter struct {
+	Page     int    `json:"page"`      // 页码，从1开始
+	PageSize int    `json:"page_size"` // 每页条数
+	Group    string `json:"group"`     // 渠道组端点
+	Channel  string `json:"channel"`   // 渠道名称
+	Key      string `json:"key"`       // 网关客户端密钥名称
+	Model    string `json:"model"`     // 模型（匹配原始模型或映射后的模型）
+	Status   string `json:"status"`    // 状态：success|failure|aborted，为空表示全部
+	Start    int64  `json:"start"`     // 开始时间（毫秒时间戳），0表示不限制
+	End      int64  `json:"end"`       // 结束时间（毫秒时间戳），0表示不限制
+}
+
+// Page 分页查询结果
+type Page struct {
+	Total    int       `json:"total"`     // 符合条件的总条数
+	Page     int       `json:"page"`      // 当前页码
+	PageSize int       `json:"page_size"` // 每页条数
+	Records  []*Record `json:"records"`   // 当前页记录，按时间倒序
+}
+
+// match 判断记录是否符合查询条件
+func (f *Filter) match(r *Record) bool {
+	if f.Group != "" && r.Group != f.Group {
+		return false
+	}
+	if f.Channel != "" && r.Channel != f.Channel {
+		return false
+	}
+	if f.Key != "" && r.Key != f.Key {
+		return false
+	}
+	if f.Model != "" && r.OriginalModel != f.Model && r.MappedModel != f.Model {
+		return false
+	}
+	switch f.Status {
+	case "success":
+		if !r.Success {
+			return false
+		}
+	case "failure":
+		if r.Success || r.Aborted {
+			return false
+		}
+	case "aborted":
+		if !r.Aborted {
+			return false
+		}
+	}
+	if f.Start > 0 && r.Time < f.Start {
+		return false
+	}
+	if f.End > 0 && r.Time > f.End {
+		return false
+	}
+	return true
+}
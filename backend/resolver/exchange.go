@@ -0,0 +1,87 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// exchangeHTTPS 通过 DNS-over-HTTPS 查询（RFC 8484）
+func (r *Resolver) exchangeHTTPS(ctx context.Context, server string, query []byte) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/dns-message")
+	request.Header.Set("Accept", "application/dns-message")
+
+	response, err := r.doh.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH 服务器返回 %s", response.Status)
+	}
+	return io.ReadAll(io.LimitReader(response.Body, 64<<10))
+}
+
+// exchangeDNS 通过 UDP 查询，应答被截断时改用 TCP
+func exchangeDNS(ctx context.Context, server string, query []byte) ([]byte, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	answer := make([]byte, 1232)
+	n, err := conn.Read(answer)
+	if err != nil {
+		return nil, err
+	}
+	answer = answer[:n]
+
+	var parser dnsmessage.Parser
+	header, err := parser.Start(answer)
+	if err != nil || !header.Truncated {
+		return answer, nil
+	}
+
+	tcp, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer tcp.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = tcp.SetDeadline(deadline)
+	}
+	packet := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := tcp.Write(append(packet, query...)); err != nil {
+		return nil, err
+	}
+	length := make([]byte, 2)
+	if _, err := io.ReadFull(tcp, length); err != nil {
+		return nil, err
+	}
+	answer = make([]byte, binary.BigEndian.Uint16(length))
+	if _, err := io.ReadFull(tcp, answer); err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
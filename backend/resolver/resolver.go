@@ -0,0 +1,223 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	minTTL       = 30 * time.Second // 缓存时间下限，避免频繁查询
+	maxTTL       = 10 * time.Minute // 缓存时间上限，及时跟随上游地址变化
+	queryTimeout = 5 * time.Second  // 单个 DNS 服务器的查询超时
+)
+
+// entry 解析结果缓存
+type entry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+// Resolver 不经过系统解析和 hosts 文件的域名解析器，Host 模式下用于出站连接，
+// 避免被劫持的域名解析回本机造成请求循环
+type Resolver struct {
+	servers []string
+	doh     *http.Client
+	cache   map[string]entry
+	mu      sync.RWMutex
+}
+
+// New 创建解析器，servers 中 https:// 开头的为 DNS-over-HTTPS，其他为普通 DNS
+func New(servers []string) *Resolver {
+	return &Resolver{
+		servers: servers,
+		// DoH 服务器本身使用系统解析，不会出现在被劫持的域名中
+		doh:   &http.Client{Timeout: queryTimeout},
+		cache: make(map[string]entry),
+	}
+}
+
+// LookupIP 解析域名，优先返回缓存，IPv4 地址排在前面
+func (r *Resolver) LookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+
+	r.mu.RLock()
+	cached, ok := r.cache[name]
+	r.mu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.ips, nil
+	}
+
+	var errs []error
+	for _, server := range r.servers {
+		ips, ttl, err := r.query(ctx, server, name)
+		if err != nil {
+			slog.Debug("DNS 查询失败", "server", server, "host", name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", server, err))
+			continue
+		}
+		r.mu.Lock()
+		r.cache[name] = entry{ips: ips, expires: time.Now().Add(min(max(ttl, minTTL), maxTTL))}
+		r.mu.Unlock()
+		slog.Debug("DNS 查询成功", "server", server, "host", name, "ips", ips)
+		return ips, nil
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("未配置 DNS 服务器")
+	}
+	return nil, fmt.Errorf("解析 %s 失败: %w", name, errors.Join(errs...))
+}
+
+// Exchange 将原始 DNS 查询依次转发给各个服务器，返回第一个成功的应答，用于本地 DNS 服务转发
+func (r *Resolver) Exchange(ctx context.Context, query []byte) ([]byte, error) {
+	var errs []error
+	for _, server := range r.servers {
+		ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+		var answer []byte
+		var err error
+		if strings.HasPrefix(server, "https://") {
+			answer, err = r.exchangeHTTPS(ctx, server, query)
+		} else {
+			answer, err = exchangeDNS(ctx, server, query)
+		}
+		cancel()
+		if err == nil {
+			return answer, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", server, err))
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("未配置 DNS 服务器")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// DialContext 使用解析结果依次连接各个地址
+func (r *Resolver) DialContext(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := r.LookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// query 向单个服务器查询 A 和 AAAA 记录
+func (r *Resolver) query(ctx context.Context, server, name string) ([]net.IP, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var ips []net.IP
+	var ttl time.Duration
+	var errs []error
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		found, t, err := r.exchange(ctx, server, name, qtype)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(found) > 0 && (ttl == 0 || t < ttl) {
+			ttl = t
+		}
+		ips = append(ips, found...)
+	}
+	if len(ips) == 0 {
+		if len(errs) > 0 {
+			return nil, 0, errors.Join(errs...)
+		}
+		return nil, 0, fmt.Errorf("没有解析记录")
+	}
+	return ips, ttl, nil
+}
+
+// exchange 发送一次 DNS 查询并解析应答中的地址
+func (r *Resolver) exchange(ctx context.Context, server, name string, qtype dnsmessage.Type) ([]net.IP, time.Duration, error) {
+	fqdn, err := dnsmessage.NewName(name + ".")
+	if err != nil {
+		return nil, 0, err
+	}
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: fqdn, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+
+	var answer []byte
+	if strings.HasPrefix(server, "https://") {
+		// RFC 8484 要求 DoH 查询的 ID 为 0，便于缓存
+		packed, err := msg.Pack()
+		if err != nil {
+			return nil, 0, err
+		}
+		answer, err = r.exchangeHTTPS(ctx, server, packed)
+		if err != nil {
+			return nil, 0, err
+		}
+	} else {
+		msg.Header.ID = uint16(time.Now().UnixNano())
+		packed, err := msg.Pack()
+		if err != nil {
+			return nil, 0, err
+		}
+		answer, err = exchangeDNS(ctx, server, packed)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return parseAnswer(answer, msg.Header.ID)
+}
+
+// parseAnswer 解析应答中的 A/AAAA 记录，返回地址和最小 TTL
+func parseAnswer(data []byte, id uint16) ([]net.IP, time.Duration, error) {
+	var reply dnsmessage.Message
+	if err := reply.Unpack(data); err != nil {
+		return nil, 0, err
+	}
+	if reply.Header.ID != id {
+		return nil, 0, fmt.Errorf("应答 ID 不匹配")
+	}
+	if reply.Header.RCode != dnsmessage.RCodeSuccess && reply.Header.RCode != dnsmessage.RCodeNameError {
+		return nil, 0, fmt.Errorf("DNS 服务器返回错误: %s", reply.Header.RCode)
+	}
+
+	var ips []net.IP
+	var ttl uint32
+	for _, answer := range reply.Answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(body.AAAA[:]))
+		default:
+			continue
+		}
+		if ttl == 0 || answer.Header.TTL < ttl {
+			ttl = answer.Header.TTL
+		}
+	}
+	return ips, time.Duration(ttl) * time.Second, nil
+}
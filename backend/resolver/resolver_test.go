@@ -0,0 +1,178 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// buildReply 构造应答报文
+func buildReply(t *testing.T, header dnsmessage.Header, answers ...dnsmessage.Resource) []byte {
+	t.Helper()
+	header.Response = true
+	msg := dnsmessage.Message{Header: header, Answers: answers}
+	data, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func resource(name string, ttl uint32, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   body,
+	}
+}
+
+func TestParseAnswer(t *testing.T) {
+	const name = "api.openai.com."
+	tests := []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		id      uint16
+		wantIPs []string
+		wantTTL time.Duration
+		wantErr bool
+	}{
+		{
+			name: "A and AAAA with minimum ttl",
+			data: func(t *testing.T) []byte {
+				return buildReply(t, dnsmessage.Header{ID: 7},
+					resource(name, 300, &dnsmessage.AResource{A: [4]byte{1, 2, 3, 4}}),
+					resource(name, 60, &dnsmessage.AAAAResource{AAAA: [16]byte(net.ParseIP("2001:db8::1"))}),
+				)
+			},
+			id:      7,
+			wantIPs: []string{"1.2.3.4", "2001:db8::1"},
+			wantTTL: 60 * time.Second,
+		},
+		{
+			name: "cname ignored",
+			data: func(t *testing.T) []byte {
+				return buildReply(t, dnsmessage.Header{ID: 1},
+					resource(name, 5, &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("edge.example.net.")}),
+					resource("edge.example.net.", 120, &dnsmessage.AResource{A: [4]byte{5, 6, 7, 8}}),
+				)
+			},
+			id:      1,
+			wantIPs: []string{"5.6.7.8"},
+			wantTTL: 120 * time.Second,
+		},
+		{
+			name: "nxdomain is empty",
+			data: func(t *testing.T) []byte {
+				return buildReply(t, dnsmessage.Header{ID: 2, RCode: dnsmessage.RCodeNameError})
+			},
+			id: 2,
+		},
+		{
+			name: "server failure",
+			data: func(t *testing.T) []byte {
+				return buildReply(t, dnsmessage.Header{ID: 3, RCode: dnsmessage.RCodeServerFailure})
+			},
+			id:      3,
+			wantErr: true,
+		},
+		{
+			name: "id mismatch",
+			data: func(t *testing.T) []byte {
+				return buildReply(t, dnsmessage.Header{ID: 4},
+					resource(name, 60, &dnsmessage.AResource{A: [4]byte{1, 2, 3, 4}}))
+			},
+			id:      5,
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			data:    func(*testing.T) []byte { return []byte{0, 1, 2} },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ips, ttl, err := parseAnswer(tt.data(t), tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(ips) != len(tt.wantIPs) {
+				t.Fatalf("ips = %v, want %v", ips, tt.wantIPs)
+			}
+			for i, ip := range ips {
+				if !ip.Equal(net.ParseIP(tt.wantIPs[i])) {
+					t.Errorf("ips[%d] = %s, want %s", i, ip, tt.wantIPs[i])
+				}
+			}
+			if ttl != tt.wantTTL {
+				t.Errorf("ttl = %s, want %s", ttl, tt.wantTTL)
+			}
+		})
+	}
+}
+
+// serveDNS 启动本地 UDP DNS 服务，A 查询应答 10.0.0.1，返回地址和收到的查询数
+func serveDNS(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	var queries atomic.Int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			queries.Add(1)
+			var msg dnsmessage.Message
+			if msg.Unpack(buf[:n]) != nil {
+				continue
+			}
+			msg.Header.Response = true
+			if q := msg.Questions[0]; q.Type == dnsmessage.TypeA {
+				msg.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 120},
+					Body:   &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}},
+				}}
+			}
+			data, _ := msg.Pack()
+			_, _ = conn.WriteTo(data, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), &queries
+}
+
+func TestLookupIPCachesResult(t *testing.T) {
+	addr, queries := serveDNS(t)
+	r := New([]string{addr})
+
+	for range 2 {
+		ips, err := r.LookupIP(context.Background(), "API.OpenAI.com.")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ips) != 1 || !ips[0].Equal(net.IPv4(10, 0, 0, 1)) {
+			t.Fatalf("ips = %v", ips)
+		}
+	}
+	// A 和 AAAA 各查询一次，第二次命中缓存
+	if got := queries.Load(); got != 2 {
+		t.Errorf("queries = %d, want 2", got)
+	}
+	if ips, err := r.LookupIP(context.Background(), "192.0.2.1"); err != nil || !ips[0].Equal(net.ParseIP("192.0.2.1")) {
+		t.Errorf("LookupIP(ip) = %v, %v", ips, err)
+	}
+}
+
+func TestLookupIPWithoutServers(t *testing.T) {
+	if _, err := New(nil).LookupIP(context.Background(), "api.openai.com"); err == nil {
+		t.Error("LookupIP succeeded without servers")
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/resolver"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// interceptTTL 劫持域名应答的 TTL，较短以便停止代理后尽快恢复
+const interceptTTL = 10
+
+// dnsResponder 本地 DNS 服务，渠道组端点解析到本机，其他域名转发给上游 DNS 服务器
+type dnsResponder struct {
+	conn     net.PacketConn
+	resolver *resolver.Resolver
+	match    func(host string) bool // 判断域名是否需要劫持
+	ipv4     [4]byte                // 劫持域名的 A 记录
+	ipv6     bool                   // 是否应答 ::1 的 AAAA 记录
+}
+
+// newDNSResponder 在 addr 上监听 UDP 并开始应答查询
+func newDNSResponder(addr string, r *resolver.Resolver, match func(string) bool, ipv4 net.IP, ipv6 bool) (*dnsResponder, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	d := &dnsResponder{conn: conn, resolver: r, match: match, ipv6: ipv6}
+	copy(d.ipv4[:], ipv4.To4())
+	go d.serve()
+	return d, nil
+}
+
+// serve 循环读取查询，每个查询单独处理，避免慢速转发阻塞其他查询
+func (d *dnsResponder) serve() {
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := d.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Error("本地 DNS 服务读取失败", "error", err)
+			}
+			return
+		}
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			answer, err := d.handle(query)
+			if err != nil {
+				slog.Debug("本地 DNS 查询失败", "error", err)
+				return
+			}
+			_, _ = d.conn.WriteTo(answer, addr)
+		}()
+	}
+}
+
+// handle 处理单个查询，劫持的域名直接应答，其他查询原样转发
+func (d *dnsResponder) handle(query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(strings.ToLower(question.Name.String()), ".")
+	if !d.match(name) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		answer, err := d.resolver.Exchange(ctx, query)
+		if err == nil {
+			return answer, nil
+		}
+		// 转发失败时返回 SERVFAIL，避免客户端等待超时
+		slog.Debug("本地 DNS 转发失败", "name", name, "error", err)
+		header.RCode = dnsmessage.RCodeServerFailure
+		builder, err := newReply(header, question)
+		if err != nil {
+			return nil, err
+		}
+		return builder.Finish()
+	}
+
+	slog.Debug("本地 DNS 劫持域名", "name", name, "type", question.Type)
+	header.Authoritative = true
+	builder, err := newReply(header, question)
+	if err != nil {
+		return nil, err
+	}
+	resource := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: interceptTTL}
+	switch {
+	case question.Type == dnsmessage.TypeA:
+		err = builder.AResource(resource, dnsmessage.AResource{A: d.ipv4})
+	case question.Type == dnsmessage.TypeAAAA && d.ipv6:
+		err = builder.AAAAResource(resource, dnsmessage.AAAAResource{AAAA: [16]byte(net.IPv6loopback)})
+	}
+	// 其他类型返回无记录的成功应答，避免客户端回退到上游拿到真实地址
+	if err != nil {
+		return nil, err
+	}
+	return builder.Finish()
+}
+
+// newReply 创建应答并写入原问题，返回可继续写入记录的 Builder
+func newReply(query dnsmessage.Header, question dnsmessage.Question) (*dnsmessage.Builder, error) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 query.ID,
+		Response:           true,
+		Authoritative:      query.Authoritative,
+		RecursionDesired:   query.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              query.RCode,
+	})
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	return &builder, nil
+}
+
+// Close 停止本地 DNS 服务
+func (d *dnsResponder) Close() error {
+	return d.conn.Close()
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"net"
+	"testing"
+
+	"github.com/sbgayhub/chameleon/backend/resolver"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func newQuery(t *testing.T, name string, qtype dnsmessage.Type) []byte {
+	t.Helper()
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	data, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// serveUpstream 启动本地上游 DNS 服务，所有 A 查询应答 203.0.113.9
+func serveUpstream(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var msg dnsmessage.Message
+			if msg.Unpack(buf[:n]) != nil {
+				continue
+			}
+			msg.Header.Response = true
+			msg.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: msg.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+				Body:   &dnsmessage.AResource{A: [4]byte{203, 0, 113, 9}},
+			}}
+			data, _ := msg.Pack()
+			_, _ = conn.WriteTo(data, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNSResponderHandle(t *testing.T) {
+	match := func(host string) bool { return host == "api.openai.com" }
+	upstream := resolver.New([]string{serveUpstream(t)})
+
+	tests := []struct {
+		name       string
+		responder  *dnsResponder
+		query      []byte
+		wantRCode  dnsmessage.RCode
+		wantAuth   bool
+		wantAnswer string // 空表示无记录
+		wantTTL    uint32
+	}{
+		{
+			name:       "intercept A case-insensitive",
+			responder:  &dnsResponder{match: match, ipv4: [4]byte{127, 0, 0, 1}},
+			query:      newQuery(t, "API.OpenAI.com.", dnsmessage.TypeA),
+			wantAuth:   true,
+			wantAnswer: "127.0.0.1",
+			wantTTL:    interceptTTL,
+		},
+		{
+			name:       "intercept AAAA with ipv6",
+			responder:  &dnsResponder{match: match, ipv6: true},
+			query:      newQuery(t, "api.openai.com.", dnsmessage.TypeAAAA),
+			wantAuth:   true,
+			wantAnswer: "::1",
+			wantTTL:    interceptTTL,
+		},
+		{
+			name:      "intercept AAAA without ipv6 is empty",
+			responder: &dnsResponder{match: match},
+			query:     newQuery(t, "api.openai.com.", dnsmessage.TypeAAAA),
+			wantAuth:  true,
+		},
+		{
+			name:      "intercept other type is empty",
+			responder: &dnsResponder{match: match},
+			query:     newQuery(t, "api.openai.com.", dnsmessage.TypeMX),
+			wantAuth:  true,
+		},
+		{
+			name:       "forward unmatched",
+			responder:  &dnsResponder{match: match, resolver: upstream},
+			query:      newQuery(t, "example.com.", dnsmessage.TypeA),
+			wantAnswer: "203.0.113.9",
+			wantTTL:    300,
+		},
+		{
+			name:      "forward failure is servfail",
+			responder: &dnsResponder{match: match, resolver: resolver.New(nil)},
+			query:     newQuery(t, "example.com.", dnsmessage.TypeA),
+			wantRCode: dnsmessage.RCodeServerFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.responder.handle(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var reply dnsmessage.Message
+			if err := reply.Unpack(data); err != nil {
+				t.Fatal(err)
+			}
+			if reply.Header.ID != 42 || !reply.Header.Response {
+				t.Errorf("header = %+v", reply.Header)
+			}
+			if reply.Header.RCode != tt.wantRCode || reply.Header.Authoritative != tt.wantAuth {
+				t.Errorf("rcode = %s authoritative = %v, want %s %v", reply.Header.RCode, reply.Header.Authoritative, tt.wantRCode, tt.wantAuth)
+			}
+			if len(reply.Questions) != 1 {
+				t.Fatalf("questions = %v", reply.Questions)
+			}
+			if tt.wantAnswer == "" {
+				if len(reply.Answers) != 0 {
+					t.Errorf("answers = %v, want none", reply.Answers)
+				}
+				return
+			}
+			if len(reply.Answers) != 1 {
+				t.Fatalf("answers = %v", reply.Answers)
+			}
+			var ip net.IP
+			switch body := reply.Answers[0].Body.(type) {
+			case *dnsmessage.AResource:
+				ip = body.A[:]
+			case *dnsmessage.AAAAResource:
+				ip = body.AAAA[:]
+			}
+			if !ip.Equal(net.ParseIP(tt.wantAnswer)) || reply.Answers[0].Header.TTL != tt.wantTTL {
+				t.Errorf("answer = %s ttl %d, want %s ttl %d", ip, reply.Answers[0].Header.TTL, tt.wantAnswer, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestDNSResponderIgnoresMalformedQuery(t *testing.T) {
+	d := &dnsResponder{match: func(string) bool { return true }}
+	if _, err := d.handle([]byte{1, 2, 3}); err == nil {
+		t.Error("handle accepted malformed query")
+	}
+}
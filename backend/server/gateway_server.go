@@ -0,0 +1,351 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/apikey"
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/config"
+	"github.com/sbgayhub/chameleon/backend/convert"
+	"github.com/sbgayhub/chameleon/backend/record"
+	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// GatewayServer 网关代理服务器，直接提供 OpenAI/Anthropic/Gemini 兼容接口，无需中间人代理或修改hosts
+type GatewayServer struct {
+	server     *http.Server
+	config     *config.ProxyConfig
+	keyMgr     *apikey.Manager
+	channelMgr *channel.Manager
+	statsMgr   *statistics.Manager
+	recordMgr  *record.Manager
+	running    bool
+	mu         sync.RWMutex
+}
+
+// NewGatewayServer 创建网关服务器
+func NewGatewayServer(config *config.ProxyConfig, keyMgr *apikey.Manager, channelMgr *channel.Manager, statsMgr *statistics.Manager, recordMgr *record.Manager) *GatewayServer {
+	slog.Info("创建网关代理服务器")
+	return &GatewayServer{
+		config:     config,
+		keyMgr:     keyMgr,
+		channelMgr: channelMgr,
+		statsMgr:   statsMgr,
+		recordMgr:  recordMgr,
+	}
+}
+
+// gatewayConfig 获取网关配置，旧配置文件中没有网关配置时使用默认值
+func (s *GatewayServer) gatewayConfig() *config.GatewayConfig {
+	if s.config.Gateway == nil {
+		return &config.GatewayConfig{Listen: "127.0.0.1", GroupHeader: "X-Chameleon-Group"}
+	}
+	return s.config.Gateway
+}
+
+// Start 启动服务器
+func (s *GatewayServer) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("代理服务器已在运行")
+	}
+
+	addr := net.JoinHostPort(s.gatewayConfig().Listen, strconv.Itoa(int(s.config.Port)))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("网关监听失败: %w", err)
+	}
+
+	server := &http.Server{
+		Handler:           http.HandlerFunc(s.proxyHandler),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	s.server = server
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("网关代理服务器运行出错", "error", err)
+			s.mu.Lock()
+			s.running = false
+			s.mu.Unlock()
+		}
+	}()
+
+	s.running = true
+	slog.Info("网关代理服务器启动成功", "listen", addr)
+	return nil
+}
+
+// Stop 停止服务器，服务器未运行时直接返回
+func (s *GatewayServer) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running || s.server == nil {
+		slog.Info("网关服务器未运行，无需停止")
+		return nil
+	}
+
+	slog.Info("正在停止网关服务器")
+	err := shutdown(s.server)
+	s.running = false
+	if err != nil {
+		slog.Error("网关服务器停止失败", "error", err)
+		return err
+	}
+
+	slog.Info("网关服务器已停止")
+	return nil
+}
+
+// UpdateConfig 更新服务器配置，运行中监听地址或端口变化时重新监听，其他网关配置实时生效
+func (s *GatewayServer) UpdateConfig(proxyConfig *config.ProxyConfig) error {
+	s.mu.Lock()
+	old := net.JoinHostPort(s.gatewayConfig().Listen, strconv.Itoa(int(s.config.Port)))
+	s.config = proxyConfig
+	addr := net.JoinHostPort(s.gatewayConfig().Listen, strconv.Itoa(int(s.config.Port)))
+	changed := s.running && old != addr
+	s.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	slog.Info("监听地址已更改，重新启动网关服务器", "old", old, "new", addr)
+	return restart(s)
+}
+
+// proxyHandler 代理处理函数
+func (s *GatewayServer) proxyHandler(writer http.ResponseWriter, request *http.Request) {
+	// 解析路由，去除路径中的渠道组前缀
+	group, path := s.route(request)
+	format := requestFormat(path, request.Header)
+
+	// 校验本地签发的客户端密钥，客户端无需持有上游密钥
+	key, err := s.keyMgr.Validate(clientKey(request))
+	if err != nil {
+		slog.Warn("网关请求鉴权失败", "remote", request.RemoteAddr, "path", request.URL.Path, "error", err)
+		writeGatewayError(writer, format, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if group == "" {
+		group = s.defaultGroup(format)
+	}
+	if group == "" {
+		writeGatewayError(writer, format, http.StatusNotFound, "未找到可处理该请求的渠道组")
+		return
+	}
+
+	// 校验密钥的渠道组、模型、频率和预算限制，跟踪请求的统计状态，避免成功响应写回失败时重复计为取消
+	request = request.WithContext(statistics.Track(request.Context()))
+	clientRequest := newClientRequest(request, path)
+	model := record.RequestModel(clientRequest)
+	if model == "" && format == "gemini" {
+		model = geminiModel(path)
+	}
+	if err := s.keyMgr.Authorize(key, group, model, s.statsMgr.KeyTokens(key.ID)); err != nil {
+		slog.Warn("网关请求被拒绝", "key", key.Name, "group", group, "model", model, "error", err)
+		writeGatewayError(writer, format, authorizeStatus(err), err.Error())
+		return
+	}
+
+	// 获取一个可用的渠道节点
+	spanCtx, root := telemetry.StartSpan(request.Context(), "proxy.request",
+		attribute.String("chameleon.group", group), attribute.String("chameleon.key", key.Name))
+	_, span := telemetry.StartSpan(spanCtx, "channel.select")
+	p, err := s.channelMgr.SelectChannel(group)
+	telemetry.EndSpan(span, err)
+	if err != nil {
+		slog.Error("获取代理失败", "group", group, "error", err.Error())
+		telemetry.EndSpan(root, err)
+		writeGatewayError(writer, format, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	sess := newSession(spanCtx, root, p, s.recordMgr.Begin(group, p, model))
+	sess.trace.SetKey(key.Name)
+	sess.trace.OnFinish(func(r *record.Record) {
+		s.statsMgr.UpdateKeyStatistics(key.ID, r.InputTokens, r.OutputTokens, r.Success, r.Aborted)
+	})
+	sess.trace.CaptureClientRequest(clientRequest)
+	slog.Info(fmt.Sprintf("[%s] 开始处理网关请求", p.Name), "key", key.Name, "method", request.Method, "path", path)
+
+	// 转换请求
+	converter, err := convert.Get(p.ConverterName)
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] 获取转换器失败", p.Name), "name", p.ConverterName, "error", err)
+		sess.fail(http.StatusInternalServerError, err)
+		writeGatewayError(writer, format, http.StatusInternalServerError, err.Error())
+		return
+	}
+	span = sess.span("convert.request")
+	upstream, err := converter.ConvertRequest(clientRequest, *p)
+	telemetry.EndSpan(span, err)
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] 转换请求失败", p.Name), "name", p.ConverterName, "error", err)
+		sess.fail(http.StatusInternalServerError, err)
+		writeGatewayError(writer, format, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sess.trace.CaptureUpstreamRequest(upstream)
+
+	// 发送请求
+	span = sess.span("upstream")
+	response, err := upstreamClient(sess).Do(upstream)
+	telemetry.EndSpan(span, err)
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] 请求出现错误", p.Name), "url", upstream.URL, "error", err)
+		sess.fail(http.StatusBadGateway, err)
+		writeGatewayError(writer, format, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	// 处理响应
+	response, err = sess.convertResponse(response)
+	if err != nil {
+		sess.fail(http.StatusInternalServerError, err)
+		writeGatewayError(writer, format, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeResponse(writer, sess.trace.Wrap(response))
+}
+
+// route 解析请求对应的渠道组，优先使用请求头指定的渠道组，其次为路径前缀，例如 /api.anthropic.com/v1/messages
+func (s *GatewayServer) route(request *http.Request) (group, path string) {
+	path = request.URL.Path
+	if header := s.gatewayConfig().GroupHeader; header != "" {
+		group = request.Header.Get(header)
+	}
+
+	segment, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if segment != "" && segment != "v1" && segment != "v1beta" {
+		if _, err := s.channelMgr.GetGroup(segment); err == nil {
+			path = "/" + rest
+			if group == "" {
+				group = segment
+			}
+		}
+	}
+	return group, path
+}
+
+// defaultGroup 未指定渠道组时，选择第一个与请求格式相同且可用的渠道组
+func (s *GatewayServer) defaultGroup(format string) string {
+	for _, group := range s.channelMgr.List() {
+		if group.Provider == format && group.Enabled && len(group.Channels) != 0 {
+			return group.Endpoint
+		}
+	}
+	return ""
+}
+
+// requestFormat 根据请求路径判断客户端使用的接口格式
+func requestFormat(path string, header http.Header) string {
+	switch {
+	case strings.HasPrefix(path, "/v1beta/"), strings.Contains(path, ":generateContent"), strings.Contains(path, ":streamGenerateContent"):
+		return "gemini"
+	case strings.HasPrefix(path, "/v1/messages"):
+		return "anthropic"
+	case header.Get("anthropic-version") != "":
+		return "anthropic"
+	default:
+		return "openai"
+	}
+}
+
+// geminiModel 从 Gemini 请求路径中解析模型名称，例如 /v1beta/models/gemini-pro:generateContent
+func geminiModel(path string) string {
+	_, model, ok := strings.Cut(path, "/models/")
+	if !ok {
+		return ""
+	}
+	model, _, _ = strings.Cut(model, ":")
+	return model
+}
+
+// authorizeStatus 获取密钥限制校验失败对应的状态码
+func authorizeStatus(err error) int {
+	switch {
+	case errors.Is(err, apikey.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apikey.ErrRateLimited), errors.Is(err, apikey.ErrBudgetExceeded):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusUnauthorized
+	}
+}
+
+// clientKey 从请求中提取客户端密钥，兼容各供应商的传递方式
+func clientKey(request *http.Request) string {
+	if key := request.Header.Get("X-Api-Key"); key != "" {
+		return key
+	}
+	if auth := request.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key := request.Header.Get("X-Goog-Api-Key"); key != "" {
+		return key
+	}
+	return request.URL.Query().Get("key")
+}
+
+// newClientRequest 复制客户端请求并去除本地密钥，避免转发到上游
+func newClientRequest(request *http.Request, path string) *http.Request {
+	clientRequest := request.Clone(request.Context())
+	clientRequest.RequestURI = ""
+	clientRequest.URL.Path = path
+	clientRequest.URL.RawPath = ""
+	for _, header := range []string{"X-Api-Key", "Authorization", "X-Goog-Api-Key"} {
+		clientRequest.Header.Del(header)
+	}
+	query := clientRequest.URL.Query()
+	if query.Has("key") {
+		query.Del("key")
+		clientRequest.URL.RawQuery = query.Encode()
+	}
+	return clientRequest
+}
+
+// writeGatewayError 按客户端请求格式输出错误信息
+func writeGatewayError(writer http.ResponseWriter, format string, status int, message string) {
+	var body any
+	switch format {
+	case "anthropic":
+		errorType := "api_error"
+		switch status {
+		case http.StatusUnauthorized:
+			errorType = "authentication_error"
+		case http.StatusForbidden:
+			errorType = "permission_error"
+		case http.StatusTooManyRequests:
+			errorType = "rate_limit_error"
+		}
+		body = map[string]any{"type": "error", "error": map[string]any{"type": errorType, "message": message}}
+	case "gemini":
+		body = map[string]any{"error": map[string]any{"code": status, "message": message, "status": http.StatusText(status)}}
+	default:
+		errorType := "api_error"
+		switch status {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			errorType = "invalid_request_error"
+		case http.StatusTooManyRequests:
+			errorType = "rate_limit_error"
+		}
+		body = map[string]any{"error": map[string]any{"type": errorType, "message": message}}
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	_ = json.NewEncoder(writer).Encode(body)
+}
@@ -1,14 +1,16 @@
 package server
 
 import (
+	"cmp"
 	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,21 +19,25 @@ import (
 	"github.com/sbgayhub/chameleon/backend/certificate"
 	"github.com/sbgayhub/chameleon/backend/channel"
 	"github.com/sbgayhub/chameleon/backend/config"
-	"github.com/sbgayhub/chameleon/backend/convert"
 	"github.com/sbgayhub/chameleon/backend/host"
+	"github.com/sbgayhub/chameleon/backend/record"
+	"github.com/sbgayhub/chameleon/backend/resolver"
 	"github.com/sbgayhub/chameleon/backend/statistics"
-
-	"github.com/gookit/goutil/errorx"
+	"github.com/sbgayhub/chameleon/backend/transport"
 )
 
 // HostServer Host代理服务器
 type HostServer struct {
 	server     *http.Server
-	client     *http.Client
+	handler    *mitmHandler
 	config     *config.ProxyConfig
 	hostMgr    *host.Manager
 	channelMgr *channel.Manager
 	statsMgr   *statistics.Manager
+	recordMgr  *record.Manager
+	dns        *dnsResponder // dns 方式下的本地 DNS 服务
+	redirect   bool          // 是否已添加端口重定向规则
+	unwatch    func()        // 取消订阅渠道配置变更
 	ctx        context.Context
 	cancel     context.CancelFunc
 	wg         sync.WaitGroup
@@ -40,22 +46,36 @@ type HostServer struct {
 }
 
 // NewHostServer 创建Host服务器
-func NewHostServer(config *config.ProxyConfig, hostMgr *host.Manager, channelMgr *channel.Manager, statsMgr *statistics.Manager) *HostServer {
+func NewHostServer(config *config.ProxyConfig, hostMgr *host.Manager, channelMgr *channel.Manager, statsMgr *statistics.Manager, recordMgr *record.Manager) *HostServer {
 	slog.Info("创建Host代理服务器")
-	ctx, cancel := context.WithCancel(context.Background())
 	return &HostServer{
 		server:     nil,
-		client:     &http.Client{Timeout: 3 * time.Minute},
+		handler:    newMitmHandler(channelMgr, recordMgr),
 		config:     config,
 		hostMgr:    hostMgr,
 		channelMgr: channelMgr,
 		statsMgr:   statsMgr,
-		ctx:        ctx,
-		cancel:     cancel,
+		recordMgr:  recordMgr,
 		running:    false,
 	}
 }
 
+// dnsServers 获取出站解析使用的 DNS 服务器，旧配置文件中没有解析配置时使用默认值
+func (s *HostServer) dnsServers() []string {
+	if s.config.Resolver == nil || len(s.config.Resolver.Servers) == 0 {
+		return config.DefaultDNSServers()
+	}
+	return s.config.Resolver.Servers
+}
+
+// hostConfig 获取 Host 模式配置，旧配置文件中没有该配置时使用默认值
+func (s *HostServer) hostConfig() *config.HostConfig {
+	if s.config.Host == nil {
+		return config.DefaultHostConfig()
+	}
+	return s.config.Host
+}
+
 // Start 启动服务器
 func (s *HostServer) Start() error {
 	s.mu.Lock()
@@ -65,49 +85,188 @@ func (s *HostServer) Start() error {
 		return fmt.Errorf("代理服务器已在运行")
 	}
 
-	// 先创建host
-	hosts := lo.Map(s.channelMgr.List(), func(item *channel.Group, _ int) string { return item.Endpoint })
-	if err := s.hostMgr.AddHosts(hosts); err != nil {
-		return err
+	cfg := s.hostConfig()
+	listen := cmp.Or(cfg.Listen, "127.0.0.1")
+	port := cmp.Or(cfg.Port, 443)
+	ip := net.ParseIP(listen)
+	if ip == nil || ip.To4() == nil {
+		return fmt.Errorf("Host 模式监听地址必须是 IPv4 地址: %s", listen)
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.proxyHandler)
+	mux.Handle("/", s.handler)
 
 	// 创建Host服务器
 	s.server = &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", "127.0.0.1", 443),
+		Addr:    net.JoinHostPort(listen, strconv.Itoa(int(port))),
 		Handler: mux,
 		TLSConfig: &tls.Config{
 			GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
 				slog.Debug("[host-proxy] 签发证书", "host", hello.ServerName)
-				return certificate.Store.Fetch(hello.ServerName, func() (*tls.Certificate, error) {
-					return certificate.SignHost(certificate.CA, []string{hello.ServerName})
-				})
+				return certificate.Store.Certificate(hello.ServerName)
 			},
 		},
 	}
 
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-		if err := s.server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			slog.Error("Host 代理服务器启动失败", "error", err)
+	// 域名同时劫持到 127.0.0.1 和 ::1，监听回环地址时两个地址都需要监听
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("Host 代理服务器监听失败: %w", err)
+	}
+	listeners := []net.Listener{listener}
+	if ip.IsLoopback() {
+		if listener6, err := net.Listen("tcp", net.JoinHostPort("::1", strconv.Itoa(int(port)))); err != nil {
+			slog.Warn("Host 代理服务器监听 IPv6 地址失败", "error", err)
+		} else {
+			listeners = append(listeners, listener6)
+		}
+	}
+	closeListeners := func() {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}
+
+	// 劫持的域名在本机解析到回环地址，出站连接需绕过系统解析，否则会请求回自身
+	dns := resolver.New(s.dnsServers())
+	transport.SetResolver(dns)
+	if err := s.intercept(cfg, dns, ip, len(listeners) > 1); err != nil {
+		s.release()
+		closeListeners()
+		transport.SetResolver(nil)
+		return err
+	}
+
+	if cfg.Redirect {
+		var dnsPort uint16
+		if s.dns != nil {
+			dnsPort = udpPort(s.dns.conn.LocalAddr())
+		}
+		if err := host.AddRedirect(host.Redirect{Address: listen, HTTPSPort: port, DNSPort: dnsPort, IPv6: len(listeners) > 1}); err != nil {
+			s.release()
+			closeListeners()
+			transport.SetResolver(nil)
+			return fmt.Errorf("添加端口重定向规则失败: %w", err)
 		}
-	}()
+		s.redirect = true
+	} else if port != 443 {
+		slog.Warn("Host 代理服务器未监听 443 端口，需自行将 443 端口转发到监听端口", "port", port)
+	}
+
+	server := s.server
+	for _, l := range listeners {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := server.ServeTLS(l, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Host 代理服务器运行出错", "error", err)
+			}
+		}()
+	}
 
+	// hosts 方式下渠道组变更时同步 hosts 文件，dns 方式每次查询实时匹配
+	if s.dns == nil {
+		events, unwatch := s.channelMgr.Subscribe()
+		s.unwatch = unwatch
+		go s.watchGroups(events)
+	}
+
+	// 每次启动创建新的上下文，停止后可以重新启动
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 	s.running = true
-	slog.Info("Host 代理服务器启动成功")
+	slog.Info("Host 代理服务器启动成功", "method", cfg.Method, "addr", s.server.Addr)
 	return nil
 }
 
-// Stop 停止服务器
+// intercept 按配置的方式将渠道组域名劫持到本机
+func (s *HostServer) intercept(cfg *config.HostConfig, dns *resolver.Resolver, ip net.IP, ipv6 bool) error {
+	switch cfg.Method {
+	case "dns":
+		// 本地 DNS 服务按渠道组实时匹配，支持通配符端点
+		match := func(name string) bool { return interceptHost(s.channelMgr, name) }
+		responder, err := newDNSResponder(cmp.Or(cfg.DNSListen, "127.0.0.1:5353"), dns, match, ip, ipv6)
+		if err != nil {
+			return fmt.Errorf("本地 DNS 服务监听失败: %w", err)
+		}
+		s.dns = responder
+		slog.Info("本地 DNS 服务已启动，请将系统 DNS 设置为该地址", "addr", responder.conn.LocalAddr())
+		return nil
+	case "", "hosts":
+		return s.hostMgr.AddHosts(s.hosts())
+	default:
+		return fmt.Errorf("不支持的劫持方式: %s", cfg.Method)
+	}
+}
+
+// hosts 需要写入 hosts 文件的渠道组端点，hosts 文件不支持通配符端点
+func (s *HostServer) hosts() []string {
+	return lo.FilterMap(s.channelMgr.List(), func(item *channel.Group, _ int) (string, bool) {
+		if strings.HasPrefix(item.Endpoint, "*") {
+			slog.Warn("hosts 方式不支持通配符端点，已跳过", "endpoint", item.Endpoint)
+			return "", false
+		}
+		return item.Endpoint, true
+	})
+}
+
+// watchGroups 渠道组变更后重新写入 hosts 文件，取消订阅后退出
+func (s *HostServer) watchGroups(events <-chan channel.Event) {
+	for event := range events {
+		// 渠道增删不影响端点列表
+		switch event.Kind {
+		case channel.EventChannelAdded, channel.EventChannelUpdated, channel.EventChannelDeleted:
+			continue
+		}
+		s.mu.RLock()
+		if s.running {
+			if err := s.hostMgr.AddHosts(s.hosts()); err != nil {
+				slog.Error("同步 hosts 文件失败", "error", err)
+			} else {
+				slog.Info("渠道组已变更，hosts 文件已同步", "event", event.Kind, "group", event.Group)
+			}
+		}
+		s.mu.RUnlock()
+	}
+}
+
+// release 撤销域名劫持和端口重定向，启动失败和停止时调用
+func (s *HostServer) release() error {
+	var errs []error
+	if s.unwatch != nil {
+		s.unwatch()
+		s.unwatch = nil
+	}
+	if s.redirect {
+		if err := host.RemoveRedirect(); err != nil {
+			errs = append(errs, fmt.Errorf("移除端口重定向规则失败: %w", err))
+		}
+		s.redirect = false
+	}
+	if s.dns != nil {
+		_ = s.dns.Close()
+		s.dns = nil
+	} else if err := s.hostMgr.RemoveHosts(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// udpPort 获取 UDP 地址的端口
+func udpPort(addr net.Addr) uint16 {
+	if udp, ok := addr.(*net.UDPAddr); ok {
+		return uint16(udp.Port)
+	}
+	return 0
+}
+
+// Stop 停止服务器，服务器未运行时直接返回
 func (s *HostServer) Stop() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if !s.running {
-		return fmt.Errorf("服务器未运行")
+		slog.Info("Host服务器未运行，无需停止")
+		return nil
 	}
 
 	slog.Info("正在停止Host服务器")
@@ -115,26 +274,26 @@ func (s *HostServer) Stop() error {
 	// 取消上下文
 	s.cancel()
 
-	// 关闭Host服务器
-	if s.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		if err := s.server.Shutdown(ctx); err != nil {
-			slog.Error("Host服务器停止失败", "error", err)
-			return err
-		}
+	// 关闭Host服务器，超时后强制关闭；无论是否成功都撤销域名劫持，避免请求被劫持到已停止的端口
+	var errs []error
+	if err := shutdown(s.server); err != nil {
+		slog.Error("Host服务器停止失败", "error", err)
+		errs = append(errs, fmt.Errorf("Host服务器停止失败: %w", err))
 	}
 
 	// 等待goroutine结束
 	s.wg.Wait()
 
-	// 移除hosts
-	if err := s.hostMgr.RemoveHosts(); err != nil {
-		return err
+	// 移除域名劫持和端口重定向
+	if err := s.release(); err != nil {
+		errs = append(errs, err)
 	}
+	transport.SetResolver(nil)
 
 	s.running = false
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
 	slog.Info("Host服务器已停止")
 	return nil
 }
@@ -153,134 +312,20 @@ func (s *HostServer) GetConfig() *config.ProxyConfig {
 	return s.config
 }
 
-// UpdateConfig 更新服务器配置
+// UpdateConfig 更新服务器配置，运行中 Host 模式配置或 DNS 服务器变化时重新启动
 func (s *HostServer) UpdateConfig(proxyConfig *config.ProxyConfig) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// 如果服务器正在运行且端口发生变化，需要重启
-	if s.running && s.config.Port != proxyConfig.Port {
-		slog.Warn("端口更改，需要重启服务器", "old", s.config.Port, "new", proxyConfig.Port)
-		// 这里可以实现平滑重启逻辑
-	}
-
+	oldHost, oldServers := *s.hostConfig(), s.dnsServers()
 	s.config = proxyConfig
-	slog.Info("服务器配置已更新", "port", proxyConfig.Port, "mode", proxyConfig.Mode)
-	return nil
-}
-
-// proxyHandler 代理处理函数
-func (s *HostServer) proxyHandler(writer http.ResponseWriter, request *http.Request) {
-	// 处理请求
-	req, p, err := s.handleRequest(request)
-	if err != nil {
-		slog.Error(err.Error())
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// 发送请求
-	response, err := s.client.Do(req)
-	if err != nil {
-		slog.Error("请求出现错误", "host", req.Host, "err", err.Error())
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// 处理响应
-	resp, err := s.handleResponse(p, response)
-	if err != nil {
-		slog.Error(err.Error())
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	writer.WriteHeader(resp.StatusCode)
-	for key, values := range resp.Header {
-		for _, value := range values {
-			writer.Header().Set(key, value)
-		}
-	}
-	_, _ = io.Copy(writer, resp.Body)
-	_ = resp.Body.Close()
-}
-
-func (s *HostServer) handleRequest(request *http.Request) (*http.Request, *channel.Channel, error) {
-	// 复制request
-	newRequest, _ := http.NewRequest(request.Method, "https://"+request.Host+request.URL.Path, request.Body)
-	newRequest.Header = request.Header
-	newRequest.Host = request.Host
-	// 如果请求在渠道组中，则进行代理处理，否则直接转发
-	groups := s.channelMgr.List()
-	ex := slices.ContainsFunc(groups, func(group *channel.Group) bool {
-		return group.Endpoint == request.Host && group.Enabled && len(group.Channels) != 0
-	})
-	if !ex {
-		return newRequest, nil, nil
-	}
-
-	// 获取一个可用的渠道节点
-	p, err := s.channelMgr.SelectChannel(newRequest.Host)
-	if err != nil {
-		slog.Error("获取代理失败", "error", err.Error())
-		return newRequest, nil, errorx.E("获取代理失败")
-	}
-	newRequest = newRequest.WithContext(context.WithValue(request.Context(), "proxy", p))
-
-	// 转换请求
-	converter, err := convert.Get(p.ConverterName)
-	if err != nil {
-		slog.Error("获取代理失败", "error", err.Error())
-		return nil, nil, err
-	}
-	if request, err := converter.ConvertRequest(newRequest, *p); err != nil {
-		return request, p, err
-	} else {
-		return request, p, nil
-	}
-}
+	newHost := *s.hostConfig()
+	changed := s.running && (oldHost != newHost || !slices.Equal(oldServers, s.dnsServers()))
+	s.mu.Unlock()
 
-func (s *HostServer) handleResponse(p *channel.Channel, response *http.Response) (*http.Response, error) {
-	// 如果请求在渠道组中，则进行代理处理，否则直接转发
-	//groups := s.channelMgr.List()
-
-	//endpoints := lo.FlatMap[*channel.Group, string](groups, func(group *channel.Group, index int) []string {
-	//	t := lo.Map[*channel.Channel, string](maputil.Values(group.Channels), func(item *channel.Channel, index int) string {
-	//		u, _ := url.Parse(item.URL)
-	//		return u.Host
-	//	})
-	//	return t
-	//})
-	//
-	//if !slices.Contains(endpoints, response.Request.Host) {
-	//	return response, nil
-	//}
-
-	slog.Info(fmt.Sprintf("[%s] 开始处理响应", p.Name), "status", response.StatusCode, "url", response.Request.URL)
-	if response.StatusCode != http.StatusOK {
-		statistics.UpdateStatistics(p.Name, false, 0, 0)
-		return response, nil
-	}
-
-	converter, err := convert.Get(p.ConverterName)
-	if err != nil {
-		slog.Error(fmt.Sprintf("[%s] 获取转换器失败", p.Name), "name", p.ConverterName, "error", err)
-		return nil, err
-	}
-
-	// 检查是否是 SSE 流
-	if strings.Contains(response.Header.Get("Content-Type"), "text/event-stream") {
-		response, err = converter.ConvertStream(response, *p)
-	} else {
-		response, err = converter.ConvertResponse(response, *p)
-	}
-
-	if err != nil {
-		slog.Error(fmt.Sprintf("[%s] 转换响应失败", p.Name), "name", p.ConverterName, "error", err)
-		return nil, err
-	} else {
-		slog.Info(fmt.Sprintf("[%s] 处理响应成功", p.Name), "status", response.StatusCode, "url", response.Request.URL)
-		return response, nil
+	if !changed {
+		return nil
 	}
+	slog.Info("Host 模式配置已更改，重新启动服务器", "method", newHost.Method, "port", newHost.Port)
+	return restart(s)
 }
 
 // loggingMiddleware 日志中间件
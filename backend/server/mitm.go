@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/convert"
+	"github.com/sbgayhub/chameleon/backend/record"
+	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/telemetry"
+	"github.com/sbgayhub/chameleon/backend/transport"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gookit/goutil/errorx"
+)
+
+// mitmHandler 处理中间人解密后的请求，Host 模式和 SOCKS5 模式共用
+type mitmHandler struct {
+	channelMgr *channel.Manager
+	recordMgr  *record.Manager
+}
+
+func newMitmHandler(channelMgr *channel.Manager, recordMgr *record.Manager) *mitmHandler {
+	return &mitmHandler{
+		channelMgr: channelMgr,
+		recordMgr:  recordMgr,
+	}
+}
+
+// ServeHTTP 处理解密后的请求，渠道组请求转换后发往渠道，其他请求原样转发
+func (s *mitmHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	// 处理请求
+	req, sess, err := s.handleRequest(request)
+	if err != nil {
+		slog.Error(err.Error())
+		sess.fail(http.StatusInternalServerError, err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 发送请求
+	var span trace.Span
+	if sess != nil {
+		span = sess.span("upstream")
+	}
+	response, err := upstreamClient(sess).Do(req)
+	if span != nil {
+		telemetry.EndSpan(span, err)
+	}
+	if err != nil {
+		slog.Error("请求出现错误", "host", req.Host, "err", err.Error())
+		sess.fail(http.StatusBadGateway, err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 非渠道组请求直接转发
+	resp := response
+	if sess != nil {
+		// 处理响应
+		resp, err = sess.convertResponse(response)
+		if err != nil {
+			slog.Error(err.Error())
+			sess.fail(http.StatusInternalServerError, err)
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp = sess.trace.Wrap(resp)
+	}
+	writeResponse(writer, resp)
+}
+
+func (s *mitmHandler) handleRequest(request *http.Request) (*http.Request, *session, error) {
+	// 复制request，上游请求继承客户端连接的上下文，客户端断开时取消上游请求
+	newRequest, _ := http.NewRequestWithContext(request.Context(), request.Method, "https://"+request.Host+request.URL.Path, request.Body)
+	newRequest.Header = request.Header
+	newRequest.Host = request.Host
+	// 如果请求在渠道组中，则进行代理处理，否则直接转发
+	group, ok := activeGroup(s.channelMgr, request.Host)
+	if !ok {
+		return newRequest, nil, nil
+	}
+
+	// 获取一个可用的渠道节点，跟踪请求的统计状态，避免成功响应写回失败时重复计为取消
+	request = request.WithContext(statistics.Track(request.Context()))
+	spanCtx, root := telemetry.StartSpan(request.Context(), "proxy.request", attribute.String("chameleon.group", group.Endpoint))
+	_, span := telemetry.StartSpan(spanCtx, "channel.select")
+	p, err := s.channelMgr.SelectChannel(group.Endpoint)
+	telemetry.EndSpan(span, err)
+	if err != nil {
+		slog.Error("获取代理失败", "error", err.Error())
+		telemetry.EndSpan(root, err)
+		return newRequest, nil, errorx.E("获取代理失败")
+	}
+	sess := newSession(spanCtx, root, p, s.recordMgr.Begin(group.Endpoint, p, record.RequestModel(newRequest)))
+	sess.trace.CaptureClientRequest(newRequest)
+	newRequest = newRequest.WithContext(context.WithValue(request.Context(), "proxy", p))
+
+	// 转换请求
+	converter, err := convert.Get(p.ConverterName)
+	if err != nil {
+		slog.Error("获取代理失败", "error", err.Error())
+		return nil, sess, err
+	}
+	span = sess.span("convert.request")
+	request, err = converter.ConvertRequest(newRequest, *p)
+	telemetry.EndSpan(span, err)
+	if err != nil {
+		return request, sess, err
+	} else {
+		sess.trace.CaptureUpstreamRequest(request)
+		return request, sess, nil
+	}
+}
+
+// upstreamClient 获取发送上游请求的客户端，渠道组请求使用渠道的出站设置，其他请求使用全局设置
+func upstreamClient(sess *session) *http.Client {
+	var opts transport.Options
+	if sess != nil {
+		opts = sess.channel.Outbound()
+	}
+	return transport.Client(opts)
+}
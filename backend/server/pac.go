@@ -0,0 +1,50 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+)
+
+// handlePAC 提供根据渠道组实时生成的 PAC 文件，只有渠道组中的域名经过代理，其他请求直连
+func (s *ProxyServer) handlePAC(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/proxy.pac" && request.URL.Path != "/wpad.dat" {
+		http.Error(writer, "This is a proxy server. Does not respond to non-proxy requests.", http.StatusInternalServerError)
+		return
+	}
+	slog.Debug("[http-proxy] 获取PAC文件", "remote", request.RemoteAddr)
+	writer.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+	writer.Header().Set("Cache-Control", "no-cache")
+	s.mu.RLock()
+	port := s.config.Port
+	s.mu.RUnlock()
+	_, _ = writer.Write([]byte(pacScript(s.channelMgr.List(), fmt.Sprintf("127.0.0.1:%d", port))))
+}
+
+// pacScript 生成 PAC 脚本，通配符端点 *.example.com 匹配其任意子域名
+func pacScript(groups []*channel.Group, proxy string) string {
+	var conditions []string
+	for _, group := range groups {
+		endpoint := strings.ToLower(group.Endpoint)
+		if suffix, ok := strings.CutPrefix(endpoint, "*"); ok && strings.HasPrefix(suffix, ".") {
+			conditions = append(conditions, fmt.Sprintf("dnsDomainIs(host, %q)", suffix))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("host == %q", endpoint))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("function FindProxyForURL(url, host) {\n")
+	b.WriteString("    host = host.toLowerCase();\n")
+	if len(conditions) != 0 {
+		fmt.Fprintf(&b, "    if (%s) {\n", strings.Join(conditions, " ||\n        "))
+		fmt.Fprintf(&b, "        return \"PROXY %s\";\n", proxy)
+		b.WriteString("    }\n")
+	}
+	b.WriteString("    return \"DIRECT\";\n")
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+)
+
+func TestPacScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  []*channel.Group
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "no groups",
+			want:    []string{"function FindProxyForURL(url, host) {", `return "DIRECT";`},
+			notWant: []string{"PROXY"},
+		},
+		{
+			name:   "exact endpoint lower-cased",
+			groups: []*channel.Group{{Endpoint: "API.OpenAI.com"}},
+			want:   []string{`host == "api.openai.com"`, `return "PROXY 127.0.0.1:9527";`, `return "DIRECT";`},
+		},
+		{
+			name:    "wildcard endpoint",
+			groups:  []*channel.Group{{Endpoint: "*.openai.azure.com"}, {Endpoint: "api.anthropic.com"}},
+			want:    []string{`dnsDomainIs(host, ".openai.azure.com")`, `host == "api.anthropic.com"`, " ||\n"},
+			notWant: []string{`"*.openai.azure.com"`},
+		},
+		{
+			name:   "quotes escaped",
+			groups: []*channel.Group{{Endpoint: `bad"host`}},
+			want:   []string{`host == "bad\"host"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := pacScript(tt.groups, "127.0.0.1:9527")
+			for _, want := range tt.want {
+				if !strings.Contains(script, want) {
+					t.Errorf("script missing %q:\n%s", want, script)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(script, notWant) {
+					t.Errorf("script contains %q:\n%s", notWant, script)
+				}
+			}
+		})
+	}
+}
@@ -6,17 +6,20 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
-	"slices"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/sbgayhub/chameleon/backend/certificate"
 	"github.com/sbgayhub/chameleon/backend/channel"
 	"github.com/sbgayhub/chameleon/backend/config"
 	"github.com/sbgayhub/chameleon/backend/convert"
+	"github.com/sbgayhub/chameleon/backend/record"
 	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/telemetry"
+	"github.com/sbgayhub/chameleon/backend/transport"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/elazarl/goproxy"
 	"github.com/gookit/goutil/errorx"
@@ -27,13 +30,14 @@ type ProxyServer struct {
 	server     *http.Server
 	statsMgr   *statistics.Manager
 	channelMgr *channel.Manager
+	recordMgr  *record.Manager
 	ctx        context.Context
 	cancel     context.CancelFunc
 	running    bool
-	mu         sync.Mutex
+	mu         sync.RWMutex
 }
 
-func NewProxyServer(config *config.ProxyConfig, channelMgr *channel.Manager, statsMgr *statistics.Manager) *ProxyServer {
+func NewProxyServer(config *config.ProxyConfig, channelMgr *channel.Manager, statsMgr *statistics.Manager, recordMgr *record.Manager) *ProxyServer {
 	slog.Info("创建Http代理服务器")
 	ctx, cancel := context.WithCancel(context.Background())
 	return &ProxyServer{
@@ -41,6 +45,7 @@ func NewProxyServer(config *config.ProxyConfig, channelMgr *channel.Manager, sta
 		config:     config,
 		statsMgr:   statsMgr,
 		channelMgr: channelMgr,
+		recordMgr:  recordMgr,
 		ctx:        ctx,
 		cancel:     cancel,
 		running:    false,
@@ -56,9 +61,17 @@ func (s *ProxyServer) Start() error {
 
 	ps := goproxy.NewProxyHttpServer()
 	ps.CertStore = certificate.Store
+	// 直接转发的请求和隧道按全局出站配置连接
+	ps.Tr = transport.Default()
+	ps.ConnectDial = func(network, addr string) (net.Conn, error) {
+		return transport.Dial(context.Background(), network, addr)
+	}
 	// 中间人代理，处理https请求
 	ps.OnRequest().HandleConnect(s.handleConnect())
 
+	// 直接访问代理端口时提供 PAC 文件
+	ps.NonproxyHandler = http.HandlerFunc(s.handlePAC)
+
 	// 处理请求
 	ps.OnRequest().Do(s.handRequest())
 
@@ -74,8 +87,9 @@ func (s *ProxyServer) Start() error {
 	s.running = true
 	slog.Info("Http 代理服务器启动成功", "监听端口", s.config.Port)
 
+	server := s.server
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("Http 代理服务器运行出错", "error", err)
 			s.mu.Lock()
 			s.running = false
@@ -87,22 +101,23 @@ func (s *ProxyServer) Start() error {
 
 func (s *ProxyServer) Stop() error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if !s.running || s.server == nil {
+		s.mu.Unlock()
 		slog.Info("代理服务器未运行，无需停止")
 		return nil
 	}
+	server := s.server
+	s.mu.Unlock()
 
 	slog.Info("正在停止代理服务器")
 
-	// 先设置运行状态为 false
-	s.running = false
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// 等待请求完成时不持有锁，避免正在处理的 PAC 请求等待锁导致无法优雅关闭
+	err := shutdown(server)
 
-	if err := s.server.Shutdown(ctx); err != nil {
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+	if err != nil {
 		slog.Error("代理服务器停止失败", "error", err)
 		return errorx.With(err, "代理服务器停止失败")
 	}
@@ -111,20 +126,30 @@ func (s *ProxyServer) Stop() error {
 	return nil
 }
 
-func (s *ProxyServer) handleConnect() goproxy.FuncHttpsHandler {
-	var hosts []string
-	for _, group := range s.channelMgr.List() {
-		hosts = append(hosts, group.Endpoint+":443")
+// UpdateConfig 更新服务器配置，运行中端口变化时重新监听
+func (s *ProxyServer) UpdateConfig(proxyConfig *config.ProxyConfig) error {
+	s.mu.Lock()
+	changed := s.running && s.config.Port != proxyConfig.Port
+	old := s.config.Port
+	s.config = proxyConfig
+	s.mu.Unlock()
+
+	if !changed {
+		return nil
 	}
+	slog.Info("端口已更改，重新启动代理服务器", "old", old, "new", proxyConfig.Port)
+	return restart(s)
+}
+
+func (s *ProxyServer) handleConnect() goproxy.FuncHttpsHandler {
 	// 只有在渠道组中的host才进行mitm中间人代理，其他直接放行
 	return func(host string, ctx *goproxy.ProxyCtx) (*goproxy.ConnectAction, string) {
-		if slices.Contains(hosts, host) {
+		name, port, err := net.SplitHostPort(host)
+		if err == nil && port == "443" && interceptHost(s.channelMgr, name) {
 			return &goproxy.ConnectAction{Action: goproxy.ConnectMitm, TLSConfig: func(host string, ctx *goproxy.ProxyCtx) (*tls.Config, error) {
 				return &tls.Config{GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
 					slog.Debug("[http-proxy] 签发证书", "host", hello.ServerName)
-					return certificate.Store.Fetch(hello.ServerName, func() (*tls.Certificate, error) {
-						return certificate.SignHost(certificate.CA, []string{hello.ServerName})
-					})
+					return certificate.Store.Certificate(hello.ServerName)
 				}}, nil
 			}}, host
 			//return goproxy.MitmConnect, host
@@ -140,39 +165,64 @@ func (s *ProxyServer) handRequest() goproxy.FuncReqHandler {
 		slog.Debug("请求进入", "method", request.Method, "host", request.Host)
 
 		// 如果请求在渠道组中，则进行代理处理，否则直接转发
-		groups := s.channelMgr.List()
-		ex := slices.ContainsFunc(groups, func(group *channel.Group) bool {
-			return group.Endpoint == request.Host && group.Enabled && len(group.Channels) != 0
-		})
-		if !ex {
+		group, ok := activeGroup(s.channelMgr, request.Host)
+		if !ok {
 			return request, nil
 		}
 
-		// 获取一个可用的渠道节点
-		p, err := s.channelMgr.SelectChannel(request.Host)
+		// 获取一个可用的渠道节点，跟踪请求的统计状态，避免成功响应写回失败时重复计为取消
+		request = request.WithContext(statistics.Track(request.Context()))
+		spanCtx, root := telemetry.StartSpan(request.Context(), "proxy.request", attribute.String("chameleon.group", group.Endpoint))
+		_, span := telemetry.StartSpan(spanCtx, "channel.select")
+		p, err := s.channelMgr.SelectChannel(group.Endpoint)
+		telemetry.EndSpan(span, err)
 		if err != nil {
 			slog.Error("获取代理失败", "error", err.Error())
+			telemetry.EndSpan(root, err)
 			return request, goproxy.NewResponse(request, goproxy.ContentTypeText, http.StatusInternalServerError, err.Error())
 		}
 
-		ctx.UserData = p
+		sess := newSession(spanCtx, root, p, s.recordMgr.Begin(group.Endpoint, p, record.RequestModel(request)))
+		sess.trace.CaptureClientRequest(request)
+		ctx.UserData = sess
 		slog.Info(fmt.Sprintf("[%s] 开始处理请求", p.Name), "method", request.Method, "url", request.URL)
 
 		// 转换请求
 		converter, err := convert.Get(p.ConverterName)
 		if err != nil {
 			slog.Error(fmt.Sprintf("[%s] 获取转换器失败", p.Name), "name", p.ConverterName, "error", err)
+			sess.trace.Fail(http.StatusInternalServerError, err)
 			return nil, goproxy.NewResponse(request, goproxy.ContentTypeText, http.StatusInternalServerError, err.Error())
 		}
 
-		if request, err := converter.ConvertRequest(request, *p); err != nil {
+		span = sess.span("convert.request")
+		upstream, err := converter.ConvertRequest(request, *p)
+		telemetry.EndSpan(span, err)
+		if err != nil {
 			slog.Error(fmt.Sprintf("[%s] 转换请求失败", p.Name), "name", p.ConverterName, "error", err)
+			sess.trace.Fail(http.StatusInternalServerError, err)
 			return request, goproxy.NewResponse(request, goproxy.ContentTypeText, http.StatusInternalServerError, err.Error())
 		} else {
-			slog.Info(fmt.Sprintf("[%s] 处理请求成功", p.Name), "url", request.URL)
-			ctx.Req = request
-			return request, nil
+			slog.Info(fmt.Sprintf("[%s] 处理请求成功", p.Name), "url", upstream.URL)
+			ctx.Req = upstream
+			ctx.RoundTripper = s.roundTripper(sess)
+			sess.trace.CaptureUpstreamRequest(upstream)
+			return upstream, nil
+		}
+	}
+}
+
+// roundTripper 发送上游请求，MITM 模式下上游请求失败时不会进入响应处理，需要在此记录
+func (s *ProxyServer) roundTripper(sess *session) goproxy.RoundTripperFunc {
+	return func(request *http.Request, ctx *goproxy.ProxyCtx) (*http.Response, error) {
+		span := sess.span("upstream")
+		response, err := transport.RoundTripper(sess.channel.Outbound()).RoundTrip(request)
+		telemetry.EndSpan(span, err)
+		if err != nil {
+			slog.Error(fmt.Sprintf("[%s] 请求出现错误", sess.channel.Name), "url", request.URL, "error", err)
+			sess.trace.Fail(http.StatusBadGateway, err)
 		}
+		return response, err
 	}
 }
 
@@ -184,41 +234,52 @@ func (s *ProxyServer) handleResponse() goproxy.FuncRespHandler {
 		if ctx.UserData == nil {
 			return response
 		}
-		p, ok := ctx.UserData.(*channel.Channel)
+		sess, ok := ctx.UserData.(*session)
 		if !ok {
 			return response
 		}
+		p := sess.channel
+
+		// 上游请求失败，已在 roundTripper 中记录
+		if response == nil {
+			return response
+		}
 
 		slog.Info(fmt.Sprintf("[%s] 开始处理响应", p.Name), "status", response.StatusCode, "url", request.URL)
+		sess.trace.CaptureUpstreamResponse(response)
 		if response.StatusCode != http.StatusOK {
 			statistics.UpdateStatistics(p.Name, false, 0, 0)
-			return response
+			return sess.trace.Wrap(response)
 		}
 
 		converter, err := convert.Get(p.ConverterName)
 		if err != nil {
 			slog.Error(fmt.Sprintf("[%s] 获取转换器失败", p.Name), "name", p.ConverterName, "error", err)
+			sess.trace.Fail(http.StatusInternalServerError, err)
 			return goproxy.NewResponse(request, goproxy.ContentTypeText, http.StatusInternalServerError, err.Error())
 		}
 
 		// 如果是GET请求或响应body为空，直接返回
 		if response.Request.Method == http.MethodGet || response.Body == nil {
-			return response
+			return sess.trace.Wrap(response)
 		}
 
 		// 检查是否是 SSE 流
+		span := sess.span("convert.response")
 		if strings.Contains(response.Header.Get("Content-Type"), "text/event-stream") {
 			response, err = converter.ConvertStream(response, *p)
 		} else {
 			response, err = converter.ConvertResponse(response, *p)
 		}
+		telemetry.EndSpan(span, err)
 
 		if err != nil {
 			slog.Error(fmt.Sprintf("[%s] 转换响应失败", p.Name), "name", p.ConverterName, "error", err)
+			sess.trace.Fail(http.StatusInternalServerError, err)
 			return goproxy.NewResponse(request, goproxy.ContentTypeText, http.StatusInternalServerError, err.Error())
 		} else {
 			slog.Info(fmt.Sprintf("[%s] 处理响应成功", p.Name), "status", response.StatusCode, "url", request.URL)
-			return response
+			return sess.trace.Wrap(response)
 		}
 	}
 }
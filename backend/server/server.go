@@ -1,9 +1,104 @@
 package server
 
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/config"
+)
+
+// shutdownTimeout 停止服务器时等待请求完成的时间，超时后强制关闭剩余连接
+var shutdownTimeout = 10 * time.Second
+
 type Server interface {
 	Start() error
 	Stop() error
+	// UpdateConfig 更新代理配置，运行中监听地址变化时重新监听
+	UpdateConfig(proxyConfig *config.ProxyConfig) error
 }
 
 type Status struct {
 }
+
+// writeResponse 将响应写回客户端，流式响应逐块刷新
+func writeResponse(writer http.ResponseWriter, response *http.Response) {
+	for key, values := range response.Header {
+		for _, value := range values {
+			writer.Header().Add(key, value)
+		}
+	}
+	// 响应体可能已被转换，长度由 ResponseWriter 重新计算
+	writer.Header().Del("Content-Length")
+	writer.WriteHeader(response.StatusCode)
+	if response.Body == nil {
+		return
+	}
+	defer response.Body.Close()
+
+	// 响应体实现了 io.WriterTo 时由其负责写入，可以感知客户端断开
+	if flusher, ok := writer.(http.Flusher); ok {
+		_, _ = io.Copy(flushWriter{writer, flusher}, response.Body)
+		return
+	}
+	_, _ = io.Copy(writer, response.Body)
+}
+
+// flushWriter 每次写入后立即刷新，流式响应逐块返回客户端
+type flushWriter struct {
+	io.Writer
+	flusher http.Flusher
+}
+
+func (w flushWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if err == nil {
+		w.flusher.Flush()
+	}
+	return n, err
+}
+
+// interceptHost 判断域名是否属于渠道组，属于时进行中间人代理，每次实时查询以便新增的渠道组立即生效
+func interceptHost(channelMgr *channel.Manager, host string) bool {
+	_, ok := channelMgr.MatchGroup(host)
+	return ok
+}
+
+// activeGroup 查找请求域名对应的可用渠道组，不可用时请求原样转发
+func activeGroup(channelMgr *channel.Manager, host string) (*channel.Group, bool) {
+	group, ok := channelMgr.MatchGroup(hostname(host))
+	if !ok || !group.Enabled || len(group.Channels) == 0 {
+		return nil, false
+	}
+	return group, true
+}
+
+// hostname 去除地址中的端口
+func hostname(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return host
+}
+
+// restart 停止并重新启动服务器，用于监听地址变化后重新监听
+func restart(s Server) error {
+	if err := s.Stop(); err != nil {
+		return err
+	}
+	return s.Start()
+}
+
+// shutdown 优雅关闭 HTTP 服务器，等待超时后强制关闭剩余连接，返回优雅关闭的错误
+func shutdown(server *http.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		_ = server.Close()
+	}
+	return err
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/config"
+)
+
+// freePort 获取一个当前未被占用的本地端口
+func freePort(t *testing.T) uint16 {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return uint16(l.Addr().(*net.TCPAddr).Port)
+}
+
+// shortShutdown 缩短停止服务器的等待时间
+func shortShutdown(t *testing.T) {
+	previous := shutdownTimeout
+	shutdownTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { shutdownTimeout = previous })
+}
+
+// hangingUpstream 启动一个直到测试结束才响应的上游服务器
+func hangingUpstream(t *testing.T) (*httptest.Server, <-chan struct{}) {
+	t.Helper()
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		upstream.Close()
+	})
+	return upstream, received
+}
+
+func TestShutdownClosesAfterTimeout(t *testing.T) {
+	shortShutdown(t)
+	upstream, received := hangingUpstream(t)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, err := http.Get(upstream.URL)
+		if err == nil {
+			response.Body.Close()
+		}
+	})}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(l) }()
+
+	result := make(chan error, 1)
+	go func() {
+		response, err := http.Get("http://" + l.Addr().String())
+		if err == nil {
+			response.Body.Close()
+		}
+		result <- err
+	}()
+	<-received
+
+	if err := shutdown(server); err == nil {
+		t.Fatal("shutdown() = nil with a hanging request")
+	}
+	select {
+	case err := <-result:
+		if err == nil {
+			t.Error("hanging request completed without error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed after shutdown timeout")
+	}
+}
+
+func TestProxyServerStopAfterTimeout(t *testing.T) {
+	shortShutdown(t)
+	upstream, received := hangingUpstream(t)
+	s := NewProxyServer(&config.ProxyConfig{Port: freePort(t)}, channel.NewManager(t.TempDir()), nil, nil)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	proxyURL, _ := url.Parse("http://127.0.0.1:" + strconv.Itoa(int(s.config.Port)))
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	go func() {
+		// 等待代理开始监听
+		for range 50 {
+			response, err := client.Get(upstream.URL)
+			if err == nil {
+				response.Body.Close()
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not forwarded through proxy")
+	}
+
+	if err := s.Stop(); err == nil {
+		t.Fatal("Stop() = nil with a hanging request")
+	}
+	if s.running {
+		t.Fatal("server still marked running after Stop")
+	}
+	// 强制关闭后端口已释放，可以重新启动
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatalf("second Stop() = %v", err)
+	}
+}
+
+// 请求未处理完时超时强制关闭，端口释放后可以重新启动，重复停止不报错
+func TestGatewayServerStopAfterTimeout(t *testing.T) {
+	shortShutdown(t)
+	s := NewGatewayServer(&config.ProxyConfig{Port: freePort(t)}, nil, channel.NewManager(t.TempDir()), nil, nil)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只发送部分请求头，连接保持活动状态，优雅关闭无法完成
+	conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(int(s.config.Port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("POST /v1/messages HTTP/1.1\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if err := s.Stop(); err == nil {
+		t.Fatal("Stop() = nil with an active connection")
+	}
+	if s.running {
+		t.Fatal("server still marked running after Stop")
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Stop() on stopped server = %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatalf("second Stop() = %v", err)
+	}
+}
+
+func TestSocksServerRestart(t *testing.T) {
+	s := NewSocksServer(&config.ProxyConfig{Port: freePort(t)}, channel.NewManager(t.TempDir()), nil, nil)
+	for range 2 {
+		if err := s.Start(); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		if s.running {
+			t.Fatal("server still marked running after Stop")
+		}
+	}
+}
+
+func TestHostServerRestart(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		Host:     &config.HostConfig{Method: "dns", Listen: "127.0.0.1", Port: freePort(t), DNSListen: "127.0.0.1:0"},
+		Resolver: &config.ResolverConfig{Servers: []string{"127.0.0.1:1"}},
+	}
+	s := NewHostServer(cfg, nil, channel.NewManager(t.TempDir()), nil, nil)
+	for range 2 {
+		if err := s.Start(); err != nil {
+			t.Fatal(err)
+		}
+		dnsAddr := s.dns.conn.LocalAddr().String()
+		if err := s.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		if s.IsRunning() || s.dns != nil {
+			t.Fatalf("running = %v, dns = %v after Stop", s.IsRunning(), s.dns)
+		}
+		if s.ctx.Err() == nil {
+			t.Fatal("context not cancelled after Stop")
+		}
+		// 本地 DNS 服务已关闭，端口可以重新监听
+		conn, err := net.ListenPacket("udp", dnsAddr)
+		if err != nil {
+			t.Fatalf("DNS port still in use: %v", err)
+		}
+		_ = conn.Close()
+	}
+}
+
+func TestHandlePACUsesCurrentPort(t *testing.T) {
+	s := NewProxyServer(&config.ProxyConfig{Port: 9527}, channel.NewManager(t.TempDir()), nil, nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			_ = s.UpdateConfig(&config.ProxyConfig{Port: 9600})
+		}
+	}()
+	for range 100 {
+		recorder := httptest.NewRecorder()
+		s.handlePAC(recorder, httptest.NewRequest(http.MethodGet, "/proxy.pac", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d", recorder.Code)
+		}
+	}
+	<-done
+}
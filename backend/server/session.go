@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/convert"
+	"github.com/sbgayhub/chameleon/backend/record"
+	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/telemetry"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// session 单次被代理请求的上下文
+type session struct {
+	channel *channel.Channel // 选中的渠道
+	trace   *record.Trace    // 请求记录跟踪
+	ctx     context.Context  // 根片段所在的链路追踪上下文
+}
+
+// newSession 创建请求上下文，请求结束时记录监控指标并结束根片段，客户端中途取消的请求计入渠道统计。
+// ctx 需派生自经 statistics.Track 跟踪的请求 context，响应已计入统计时不再按取消重复计数
+func newSession(ctx context.Context, root trace.Span, ch *channel.Channel, t *record.Trace) *session {
+	t.OnFinish(telemetry.Finish(root, ch))
+	t.OnFinish(func(r *record.Record) {
+		if !r.Aborted {
+			return
+		}
+		slog.Info(fmt.Sprintf("[%s] 客户端取消请求", ch.Name), "input", r.InputTokens, "output", r.OutputTokens)
+		if !statistics.Counted(ctx) {
+			statistics.UpdateAborted(ch.Name, r.InputTokens, r.OutputTokens)
+		}
+	})
+	return &session{channel: ch, trace: t, ctx: ctx}
+}
+
+// span 在根片段下开始一个子片段
+func (s *session) span(name string) trace.Span {
+	_, span := telemetry.StartSpan(s.ctx, name)
+	return span
+}
+
+// fail 以错误结束请求记录，非渠道组请求时 session 为 nil
+func (s *session) fail(status int, err error) {
+	if s == nil {
+		return
+	}
+	s.trace.Fail(status, err)
+}
+
+// convertResponse 将上游响应转换为客户端请求的格式，非 200 响应直接返回
+func (s *session) convertResponse(response *http.Response) (*http.Response, error) {
+	p := s.channel
+	slog.Info(fmt.Sprintf("[%s] 开始处理响应", p.Name), "status", response.StatusCode, "url", response.Request.URL)
+	s.trace.CaptureUpstreamResponse(response)
+	if response.StatusCode != http.StatusOK {
+		statistics.UpdateStatisticsContext(response.Request.Context(), p.Name, false, 0, 0)
+		return response, nil
+	}
+
+	converter, err := convert.Get(p.ConverterName)
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] 获取转换器失败", p.Name), "name", p.ConverterName, "error", err)
+		return nil, err
+	}
+
+	// 检查是否是 SSE 流
+	span := s.span("convert.response")
+	if strings.Contains(response.Header.Get("Content-Type"), "text/event-stream") {
+		response, err = converter.ConvertStream(response, *p)
+	} else {
+		response, err = converter.ConvertResponse(response, *p)
+	}
+	telemetry.EndSpan(span, err)
+
+	if err != nil {
+		slog.Error(fmt.Sprintf("[%s] 转换响应失败", p.Name), "name", p.ConverterName, "error", err)
+		return nil, err
+	}
+	slog.Info(fmt.Sprintf("[%s] 处理响应成功", p.Name), "status", response.StatusCode, "url", response.Request.URL)
+	return response, nil
+}
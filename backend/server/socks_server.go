@@ -0,0 +1,362 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/certificate"
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/config"
+	"github.com/sbgayhub/chameleon/backend/record"
+	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/transport"
+)
+
+// SOCKS5 协议常量，参见 RFC 1928
+const (
+	socksVersion        = 0x05
+	socksNoAuth         = 0x00
+	socksNoAcceptable   = 0xFF
+	socksCmdConnect     = 0x01
+	socksAtypIPv4       = 0x01
+	socksAtypDomain     = 0x03
+	socksAtypIPv6       = 0x04
+	socksSucceeded      = 0x00
+	socksHostUnreach    = 0x04
+	socksCmdUnsupported = 0x07
+	socksAtypUnsupport  = 0x08
+)
+
+// SocksServer SOCKS5 代理服务器，渠道组中的域名进行中间人代理，其他连接直接转发
+type SocksServer struct {
+	listener   net.Listener
+	server     *http.Server  // 处理中间人解密后的请求
+	mitm       *connListener // 中间人连接队列
+	handler    *mitmHandler
+	config     *config.ProxyConfig
+	channelMgr *channel.Manager
+	statsMgr   *statistics.Manager
+	recordMgr  *record.Manager
+	conns      map[net.Conn]struct{} // 正在转发的连接，停止时关闭
+	running    bool
+	mu         sync.RWMutex
+}
+
+// NewSocksServer 创建 SOCKS5 服务器
+func NewSocksServer(config *config.ProxyConfig, channelMgr *channel.Manager, statsMgr *statistics.Manager, recordMgr *record.Manager) *SocksServer {
+	slog.Info("创建SOCKS5代理服务器")
+	return &SocksServer{
+		handler:    newMitmHandler(channelMgr, recordMgr),
+		config:     config,
+		channelMgr: channelMgr,
+		statsMgr:   statsMgr,
+		recordMgr:  recordMgr,
+		conns:      make(map[net.Conn]struct{}),
+	}
+}
+
+// Start 启动服务器
+func (s *SocksServer) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("代理服务器已在运行")
+	}
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(int(s.config.Port)))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("SOCKS5 监听失败: %w", err)
+	}
+	s.listener = listener
+	s.mitm = newConnListener(listener.Addr())
+	s.server = &http.Server{Handler: s.handler}
+
+	server, mitm := s.server, s.mitm
+	go func() {
+		if err := server.Serve(mitm); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("SOCKS5 中间人服务出错", "error", err)
+		}
+	}()
+	go s.acceptLoop(listener)
+
+	s.running = true
+	slog.Info("SOCKS5 代理服务器启动成功", "listen", addr)
+	return nil
+}
+
+// Stop 停止服务器，服务器未运行时直接返回
+func (s *SocksServer) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		slog.Info("SOCKS5服务器未运行，无需停止")
+		return nil
+	}
+
+	slog.Info("正在停止SOCKS5服务器")
+	_ = s.listener.Close()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	clear(s.conns)
+
+	// 停止期间持有锁，新连接在 track 中等待并在停止后被拒绝
+	err := shutdown(s.server)
+	s.running = false
+	if err != nil {
+		slog.Error("SOCKS5服务器停止失败", "error", err)
+		return err
+	}
+
+	slog.Info("SOCKS5服务器已停止")
+	return nil
+}
+
+// UpdateConfig 更新服务器配置，运行中端口变化时重新监听
+func (s *SocksServer) UpdateConfig(proxyConfig *config.ProxyConfig) error {
+	s.mu.Lock()
+	changed := s.running && s.config.Port != proxyConfig.Port
+	old := s.config.Port
+	s.config = proxyConfig
+	s.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	slog.Info("端口已更改，重新启动SOCKS5服务器", "old", old, "new", proxyConfig.Port)
+	return restart(s)
+}
+
+func (s *SocksServer) acceptLoop(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Error("SOCKS5 接受连接失败", "error", err)
+			}
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn 完成 SOCKS5 握手，按目标地址选择中间人代理或直接转发
+func (s *SocksServer) handleConn(conn net.Conn) {
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+	target, err := socksHandshake(conn)
+	if err != nil {
+		slog.Debug("[socks-proxy] 握手失败", "remote", conn.RemoteAddr(), "error", err)
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	host, port, _ := net.SplitHostPort(target)
+	if port == "443" && interceptHost(s.channelMgr, host) {
+		slog.Debug("[socks-proxy] 中间人代理", "host", target)
+		if err := socksReply(conn, socksSucceeded); err != nil {
+			_ = conn.Close()
+			return
+		}
+		s.mitm.push(tls.Server(conn, &tls.Config{
+			NextProtos: []string{"http/1.1"},
+			GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+				// 客户端未发送 SNI 时使用 SOCKS5 请求中的域名
+				name := hello.ServerName
+				if name == "" {
+					name = host
+				}
+				slog.Debug("[socks-proxy] 签发证书", "host", name)
+				return certificate.Store.Certificate(name)
+			},
+		}))
+		return
+	}
+
+	upstream, err := transport.Dial(context.Background(), "tcp", target)
+	if err != nil {
+		slog.Debug("[socks-proxy] 连接目标失败", "host", target, "error", err)
+		_ = socksReply(conn, socksHostUnreach)
+		_ = conn.Close()
+		return
+	}
+	if err := socksReply(conn, socksSucceeded); err != nil {
+		_ = conn.Close()
+		_ = upstream.Close()
+		return
+	}
+	s.tunnel(conn, upstream)
+}
+
+// tunnel 在客户端和目标之间双向转发数据
+func (s *SocksServer) tunnel(conn, upstream net.Conn) {
+	if !s.track(conn, upstream) {
+		_ = conn.Close()
+		_ = upstream.Close()
+		return
+	}
+	defer s.untrack(conn, upstream)
+
+	done := make(chan struct{}, 2)
+	pipe := func(dst, src net.Conn) {
+		_, _ = io.Copy(dst, src)
+		if tcp, ok := dst.(*net.TCPConn); ok {
+			_ = tcp.CloseWrite()
+		}
+		done <- struct{}{}
+	}
+	go pipe(upstream, conn)
+	go pipe(conn, upstream)
+	<-done
+	<-done
+	_ = conn.Close()
+	_ = upstream.Close()
+}
+
+// track 记录正在转发的连接，服务器已停止时返回 false
+func (s *SocksServer) track(conns ...net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return false
+	}
+	for _, conn := range conns {
+		s.conns[conn] = struct{}{}
+	}
+	return true
+}
+
+func (s *SocksServer) untrack(conns ...net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range conns {
+		delete(s.conns, conn)
+	}
+}
+
+// socksHandshake 处理 SOCKS5 协商和 CONNECT 请求，返回目标地址
+func socksHandshake(conn net.Conn) (string, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+	if header[0] != socksVersion {
+		return "", fmt.Errorf("不支持的 SOCKS 版本: %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", err
+	}
+	// 仅监听本机地址，不需要认证
+	method := byte(socksNoAcceptable)
+	for _, m := range methods {
+		if m == socksNoAuth {
+			method = socksNoAuth
+		}
+	}
+	if _, err := conn.Write([]byte{socksVersion, method}); err != nil {
+		return "", err
+	}
+	if method == socksNoAcceptable {
+		return "", fmt.Errorf("客户端不支持无认证方式")
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return "", err
+	}
+	if request[1] != socksCmdConnect {
+		_ = socksReply(conn, socksCmdUnsupported)
+		return "", fmt.Errorf("不支持的 SOCKS 命令: %d", request[1])
+	}
+
+	var host string
+	switch request[3] {
+	case socksAtypIPv4, socksAtypIPv6:
+		ip := make([]byte, net.IPv4len)
+		if request[3] == socksAtypIPv6 {
+			ip = make([]byte, net.IPv6len)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case socksAtypDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return "", err
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", err
+		}
+		host = string(domain)
+	default:
+		_ = socksReply(conn, socksAtypUnsupport)
+		return "", fmt.Errorf("不支持的地址类型: %d", request[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// socksReply 回复 CONNECT 请求，绑定地址固定为 0.0.0.0:0
+func socksReply(conn net.Conn, status byte) error {
+	_, err := conn.Write([]byte{socksVersion, status, 0x00, socksAtypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+// connListener 将已建立的连接交给 http.Server 处理
+type connListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{addr: addr, conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+// push 提交连接，监听器已关闭时直接关闭连接
+func (l *connListener) push(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		_ = conn.Close()
+	}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sbgayhub/chameleon/backend/admin"
+	"github.com/sbgayhub/chameleon/backend/telemetry"
+)
+
+// startAdmin 按配置初始化链路追踪并启动本地管理接口
+func (svc *Service) startAdmin() {
+	if err := svc.TelemetryMgr.Start(svc.ctx); err != nil {
+		slog.Warn("初始化链路追踪失败", "error", err)
+	}
+
+	cfg := svc.ConfigMgr.GetConfig().Admin
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	// 首次启动时生成访问令牌并保存到配置文件
+	if cfg.Token == "" {
+		token, err := admin.GenerateToken()
+		if err != nil {
+			slog.Error("生成管理接口令牌失败", "error", err)
+			return
+		}
+		cfg.Token = token
+		if err := svc.ConfigMgr.Save(); err != nil {
+			slog.Warn("保存管理接口令牌失败", "error", err)
+		}
+		slog.Info("已生成管理接口访问令牌，请在 config.toml 的 [admin] 中查看")
+	}
+
+	svc.AdminServer = admin.NewServer(cfg)
+	if svc.TelemetryMgr.MetricsEnabled() {
+		svc.AdminServer.Handle("/metrics", telemetry.Handler())
+	}
+	svc.registerAPI(cfg.Token)
+	if err := svc.AdminServer.Start(); err != nil {
+		slog.Error("启动管理接口失败", "error", err)
+	}
+}
+
+// stopAdmin 停止本地管理接口并导出剩余的链路数据
+func (svc *Service) stopAdmin(ctx context.Context) {
+	if svc.AdminServer != nil {
+		if err := svc.AdminServer.Stop(); err != nil {
+			slog.Warn("停止管理接口失败", "error", err)
+		}
+	}
+	if err := svc.TelemetryMgr.Shutdown(ctx); err != nil {
+		slog.Warn("导出链路数据失败", "error", err)
+	}
+}
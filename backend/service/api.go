@@ -0,0 +1,416 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/admin"
+	"github.com/sbgayhub/chameleon/backend/apikey"
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/config"
+	"github.com/sbgayhub/chameleon/backend/record"
+	"github.com/sbgayhub/chameleon/backend/statistics"
+)
+
+// registerAPI 注册管理接口，与 Wails 绑定的方法保持一致，接口说明见 /api/openapi.json
+func (svc *Service) registerAPI(token string) {
+	mux := http.NewServeMux()
+
+	// 渠道组
+	mux.HandleFunc("GET /api/groups", svc.apiListGroups)
+	mux.HandleFunc("POST /api/groups", svc.apiAddGroup)
+	mux.HandleFunc("GET /api/groups/{group}", svc.apiGetGroup)
+	mux.HandleFunc("PUT /api/groups/{group}", svc.apiUpdateGroup)
+	mux.HandleFunc("DELETE /api/groups/{group}", svc.apiDeleteGroup)
+
+	// 渠道
+	mux.HandleFunc("POST /api/groups/{group}/channels", svc.apiAddChannel)
+	mux.HandleFunc("GET /api/groups/{group}/channels/{name}", svc.apiGetChannel)
+	mux.HandleFunc("PUT /api/groups/{group}/channels/{name}", svc.apiUpdateChannel)
+	mux.HandleFunc("DELETE /api/groups/{group}/channels/{name}", svc.apiDeleteChannel)
+	mux.HandleFunc("POST /api/groups/{group}/channels/{name}/test", svc.apiTestChannel)
+	mux.HandleFunc("GET /api/groups/{group}/channels/{name}/models", svc.apiFetchModels)
+
+	// 代理
+	mux.HandleFunc("GET /api/proxy", svc.apiProxyStatus)
+	mux.HandleFunc("POST /api/proxy/start", svc.apiStartProxy)
+	mux.HandleFunc("POST /api/proxy/stop", svc.apiStopProxy)
+
+	// 证书
+	mux.HandleFunc("GET /api/cert", svc.apiCertStatus)
+
+	// 统计与请求记录
+	mux.HandleFunc("GET /api/stats", svc.apiStats)
+	mux.HandleFunc("DELETE /api/stats", svc.apiResetStats)
+	mux.HandleFunc("GET /api/records", svc.apiQueryRecords)
+	mux.HandleFunc("GET /api/records/{id}", svc.apiGetRecord)
+	mux.HandleFunc("GET /api/records/{id}/capture", svc.apiGetCapture)
+
+	// 网关客户端密钥
+	mux.HandleFunc("GET /api/keys", svc.apiListKeys)
+	mux.HandleFunc("POST /api/keys", svc.apiGenerateKey)
+	mux.HandleFunc("PUT /api/keys/{id}/limits", svc.apiSetKeyLimits)
+	mux.HandleFunc("POST /api/keys/{id}/revoke", svc.apiRevokeKey)
+	mux.HandleFunc("DELETE /api/keys/{id}", svc.apiDeleteKey)
+
+	// 日志与配置
+	mux.HandleFunc("GET /api/logs", svc.apiLogs)
+	mux.HandleFunc("GET /api/config", svc.apiGetConfig)
+	mux.HandleFunc("PUT /api/config", svc.apiUpdateConfig)
+
+	svc.AdminServer.Handle("GET /api/openapi.json", admin.OpenAPIHandler())
+	svc.AdminServer.Handle("/api/", admin.RequireToken(token, mux))
+}
+
+func (svc *Service) apiListGroups(w http.ResponseWriter, _ *http.Request) {
+	admin.WriteJSON(w, http.StatusOK, svc.ChannelMgr.List())
+}
+
+func (svc *Service) apiAddGroup(w http.ResponseWriter, r *http.Request) {
+	var group channel.Group
+	if err := admin.ReadJSON(r, &group); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := svc.ChannelMgr.AddGroup(&group); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	svc.saveChannels(w, http.StatusCreated, &group)
+}
+
+func (svc *Service) apiGetGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := svc.ChannelMgr.GetGroup(r.PathValue("group"))
+	if err != nil {
+		admin.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, group)
+}
+
+func (svc *Service) apiUpdateGroup(w http.ResponseWriter, r *http.Request) {
+	var group channel.Group
+	if err := admin.ReadJSON(r, &group); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	group.Endpoint = r.PathValue("group")
+	if err := svc.ChannelMgr.UpdateGroup(&group); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	svc.saveChannels(w, http.StatusOK, &group)
+}
+
+func (svc *Service) apiDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := svc.ChannelMgr.DeleteGroup(r.PathValue("group")); err != nil {
+		admin.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	svc.saveChannels(w, http.StatusNoContent, nil)
+}
+
+func (svc *Service) apiAddChannel(w http.ResponseWriter, r *http.Request) {
+	var ch channel.Channel
+	if err := admin.ReadJSON(r, &ch); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := svc.ChannelMgr.AddChannel(r.PathValue("group"), &ch); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	svc.saveChannels(w, http.StatusCreated, &ch)
+}
+
+func (svc *Service) apiGetChannel(w http.ResponseWriter, r *http.Request) {
+	ch, err := svc.ChannelMgr.GetChannel(r.PathValue("group"), r.PathValue("name"))
+	if err != nil {
+		admin.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, ch)
+}
+
+func (svc *Service) apiUpdateChannel(w http.ResponseWriter, r *http.Request) {
+	var ch channel.Channel
+	if err := admin.ReadJSON(r, &ch); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ch.Name = r.PathValue("name")
+	if err := svc.ChannelMgr.UpdateChannel(r.PathValue("group"), &ch); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	svc.saveChannels(w, http.StatusOK, &ch)
+}
+
+func (svc *Service) apiDeleteChannel(w http.ResponseWriter, r *http.Request) {
+	if err := svc.ChannelMgr.DeleteChannel(r.PathValue("group"), r.PathValue("name")); err != nil {
+		admin.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	svc.saveChannels(w, http.StatusNoContent, nil)
+}
+
+func (svc *Service) apiTestChannel(w http.ResponseWriter, r *http.Request) {
+	group, name := r.PathValue("group"), r.PathValue("name")
+	if _, err := svc.ChannelMgr.GetChannel(group, name); err != nil {
+		admin.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	result, err := svc.ChannelMgr.TestChannel(group, name)
+	if err != nil {
+		admin.WriteError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, map[string]string{"result": result})
+}
+
+func (svc *Service) apiFetchModels(w http.ResponseWriter, r *http.Request) {
+	models, err := svc.ChannelMgr.FetchModels(r.PathValue("group"), r.PathValue("name"))
+	if err != nil {
+		admin.WriteError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, models)
+}
+
+func (svc *Service) apiProxyStatus(w http.ResponseWriter, _ *http.Request) {
+	admin.WriteJSON(w, http.StatusOK, svc.GetProxyStatus())
+}
+
+func (svc *Service) apiStartProxy(w http.ResponseWriter, _ *http.Request) {
+	if err := svc.StartProxy(); err != nil {
+		admin.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, svc.GetProxyStatus())
+}
+
+func (svc *Service) apiStopProxy(w http.ResponseWriter, _ *http.Request) {
+	if err := svc.StopProxy(); err != nil {
+		admin.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, svc.GetProxyStatus())
+}
+
+func (svc *Service) apiCertStatus(w http.ResponseWriter, _ *http.Request) {
+	admin.WriteJSON(w, http.StatusOK, svc.CertMgr.Status())
+}
+
+func (svc *Service) apiStats(w http.ResponseWriter, _ *http.Request) {
+	admin.WriteJSON(w, http.StatusOK, map[string]any{
+		"channels": svc.StatsMgr.GetAllStatistics(),
+		"daily":    svc.StatsMgr.GetDailyStatistics(),
+		"total":    svc.StatsMgr.GetTotalStatistics(),
+		"keys":     svc.StatsMgr.GetKeyStatistics(),
+	})
+}
+
+func (svc *Service) apiResetStats(w http.ResponseWriter, _ *http.Request) {
+	svc.StatsMgr.ResetAllStatistics()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (svc *Service) apiQueryRecords(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := record.Filter{
+		Group:   query.Get("group"),
+		Channel: query.Get("channel"),
+		Key:     query.Get("key"),
+		Model:   query.Get("model"),
+		Status:  query.Get("status"),
+	}
+	filter.Page, _ = strconv.Atoi(query.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(query.Get("page_size"))
+	filter.Start, _ = strconv.ParseInt(query.Get("start"), 10, 64)
+	filter.End, _ = strconv.ParseInt(query.Get("end"), 10, 64)
+
+	page, err := svc.RecordMgr.QueryRecords(filter)
+	if err != nil {
+		admin.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, page)
+}
+
+func (svc *Service) apiGetRecord(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		admin.WriteError(w, http.StatusBadRequest, "无效的记录ID")
+		return
+	}
+	rec, err := svc.RecordMgr.GetRecord(id)
+	if err != nil {
+		admin.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, rec)
+}
+
+func (svc *Service) apiGetCapture(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		admin.WriteError(w, http.StatusBadRequest, "无效的记录ID")
+		return
+	}
+	capture, err := svc.RecordMgr.GetCapture(id)
+	if err != nil {
+		admin.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, capture)
+}
+
+// keyUsage 客户端密钥及其用量
+type keyUsage struct {
+	*apikey.Key
+	Usage *statistics.Statistics `json:"usage,omitempty"`
+}
+
+func (svc *Service) apiListKeys(w http.ResponseWriter, _ *http.Request) {
+	usage := svc.StatsMgr.GetKeyStatistics()
+	keys := make([]keyUsage, 0)
+	for _, key := range svc.KeyMgr.List() {
+		keys = append(keys, keyUsage{Key: key, Usage: usage[key.ID]})
+	}
+	admin.WriteJSON(w, http.StatusOK, keys)
+}
+
+func (svc *Service) apiGenerateKey(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+		apikey.Limits
+	}
+	if err := admin.ReadJSON(r, &body); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := svc.KeyMgr.Generate(body.Name, body.Limits)
+	if err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusCreated, result)
+}
+
+func (svc *Service) apiSetKeyLimits(w http.ResponseWriter, r *http.Request) {
+	var limits apikey.Limits
+	if err := admin.ReadJSON(r, &limits); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := svc.KeyMgr.SetLimits(r.PathValue("id"), limits); err != nil {
+		admin.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (svc *Service) apiRevokeKey(w http.ResponseWriter, r *http.Request) {
+	if err := svc.KeyMgr.Revoke(r.PathValue("id")); err != nil {
+		admin.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (svc *Service) apiDeleteKey(w http.ResponseWriter, r *http.Request) {
+	if err := svc.KeyMgr.Delete(r.PathValue("id")); err != nil {
+		admin.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiLogs 获取最近的日志，指定 keyword 时搜索日志，指定 follow 时持续输出新日志
+func (svc *Service) apiLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	lines, _ := strconv.Atoi(query.Get("lines"))
+	if lines <= 0 {
+		lines = 200
+	}
+
+	if keyword := query.Get("keyword"); keyword != "" {
+		logs, err := svc.SearchLogs(keyword, lines)
+		if err != nil {
+			admin.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, logs)
+		return
+	}
+
+	logs, offset, err := svc.tailLogs(lines)
+	if err != nil {
+		admin.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if query.Get("follow") != "true" {
+		admin.WriteJSON(w, http.StatusOK, logs)
+		return
+	}
+
+	// 以纯文本持续输出新增日志，直到客户端断开，只读取上次偏移之后的内容
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	flusher, _ := w.(http.Flusher)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		for _, line := range logs {
+			_, _ = w.Write([]byte(line + "\n"))
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		if logs, offset, err = svc.readLogsFrom(offset); err != nil {
+			return
+		}
+	}
+}
+
+func (svc *Service) apiGetConfig(w http.ResponseWriter, _ *http.Request) {
+	admin.WriteJSON(w, http.StatusOK, svc.ConfigMgr.GetConfig())
+}
+
+func (svc *Service) apiUpdateConfig(w http.ResponseWriter, r *http.Request) {
+	// 在副本上合并请求内容，校验失败时当前配置不受影响
+	cfg := svc.ConfigMgr.GetConfig().Clone()
+	if err := admin.ReadJSON(r, cfg); err != nil {
+		admin.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := svc.ConfigMgr.UpdateConfig(cfg); err != nil {
+		status := http.StatusInternalServerError
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			status = http.StatusBadRequest
+		}
+		admin.WriteError(w, status, err.Error())
+		return
+	}
+	admin.WriteJSON(w, http.StatusOK, svc.ConfigMgr.GetConfig())
+}
+
+// saveChannels 保存渠道配置后输出响应
+func (svc *Service) saveChannels(w http.ResponseWriter, status int, v any) {
+	if err := svc.ChannelMgr.SaveToFile(); err != nil {
+		admin.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if v == nil {
+		w.WriteHeader(status)
+		return
+	}
+	admin.WriteJSON(w, status, v)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"log/slog"
+
+	"github.com/sbgayhub/chameleon/backend/certificate"
+	"github.com/sbgayhub/chameleon/backend/config"
+)
+
+// syncCertInstalled 按系统信任存储的实际状态更新配置中的证书安装标记，不支持检查的平台保留原值
+func (svc *Service) syncCertInstalled() {
+	status := svc.CertMgr.Status()
+	if status.Expired {
+		slog.Warn("CA 证书已过期，请重新生成", "expires", status.NotAfter)
+	} else if status.ExpiringSoon {
+		slog.Warn("CA 证书即将过期，请重新生成", "expires", status.NotAfter)
+	}
+
+	for _, store := range status.Stores {
+		if store.Name != certificate.StoreSystem || !store.Available {
+			continue
+		}
+		proxy := svc.ConfigMgr.GetConfig().Proxy
+		if proxy.CertInstalled == status.Installed {
+			return
+		}
+		slog.Info("CA 证书实际安装状态与配置不一致，已更新", "installed", status.Installed, "serial", status.Serial)
+		updated := *proxy
+		updated.CertInstalled = status.Installed
+		if err := svc.ConfigMgr.UpdateProxyConfig(&updated); err != nil {
+			slog.Warn("更新证书安装状态失败", "error", err)
+		}
+	}
+}
+
+// configureCertCache 按代理配置设置站点证书缓存，未配置时使用默认值
+func (svc *Service) configureCertCache(proxy *config.ProxyConfig) {
+	cert := proxy.Cert
+	if cert == nil {
+		cert = config.DefaultCertConfig()
+	}
+	svc.CertMgr.ConfigureCache(cert.KeyType, cert.CacheSize, cert.Persist)
+}
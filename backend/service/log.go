@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/sbgayhub/chameleon/backend/config"
+)
+
+// GetLogs returns the latest log entries
+func (svc *Service) GetLogs(lines int) ([]string, error) {
+	logs, _, err := svc.tailLogs(lines)
+	return logs, err
+}
+
+// tailLogs 读取最后 lines 行日志（lines <= 0 时读取全部），并返回已读取的文件偏移，
+// 未以换行结尾的最后一行可能仍在写入，留给 readLogsFrom 读取
+func (svc *Service) tailLogs(lines int) ([]string, int64, error) {
+	data, err := os.ReadFile(config.LogPath(svc.dataDir))
+	if os.IsNotExist(err) {
+		return []string{}, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取日志文件失败: %w", err)
+	}
+
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	allLines := splitLines(data)
+	// 返回最后的 N 行
+	if lines > 0 && lines < len(allLines) {
+		allLines = allLines[len(allLines)-lines:]
+	}
+	return allLines, int64(len(data)), nil
+}
+
+// readLogsFrom 读取 offset 之后新增的完整日志行并返回新的偏移，日志被清空后从头读取
+func (svc *Service) readLogsFrom(offset int64) ([]string, int64, error) {
+	file, err := os.Open(config.LogPath(svc.dataDir))
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, offset, fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, offset, fmt.Errorf("读取日志文件失败: %w", err)
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if info.Size() == offset {
+		return nil, offset, nil
+	}
+
+	data := make([]byte, info.Size()-offset)
+	if _, err := io.ReadFull(io.NewSectionReader(file, offset, int64(len(data))), data); err != nil {
+		return nil, offset, fmt.Errorf("读取日志文件失败: %w", err)
+	}
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	return splitLines(data), offset + int64(len(data)), nil
+}
+
+// splitLines 按行拆分以换行结尾的日志内容
+func splitLines(data []byte) []string {
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// SearchLogs 在日志中搜索关键词
+func (svc *Service) SearchLogs(keyword string, maxResults int) ([]string, error) {
+	if strings.TrimSpace(keyword) == "" {
+		return svc.GetLogs(1000) // 返回最近的1000行
+	}
+
+	logFile := config.LogPath(svc.dataDir)
+	if _, err := os.Stat(logFile); os.IsNotExist(err) {
+		return []string{}, nil
+	}
+
+	file, err := os.Open(logFile)
+	if err != nil {
+		return nil, fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	defer file.Close()
+
+	var results []string
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(strings.ToLower(line), strings.ToLower(keyword)) {
+			results = append(results, line)
+			if len(results) >= maxResults {
+				break
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取日志文件失败: %w", err)
+	}
+
+	return results, nil
+}
+
+// ClearLogs 清空日志文件
+func (svc *Service) ClearLogs() error {
+	logFile := config.LogPath(svc.dataDir)
+
+	// 如果文件不存在，直接返回成功
+	if _, err := os.Stat(logFile); os.IsNotExist(err) {
+		return nil
+	}
+
+	// 清空文件内容
+	file, err := os.OpenFile(logFile, os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("清空日志文件失败: %w", err)
+	}
+	defer file.Close()
+
+	slog.Info("日志文件已清空", "file", logFile)
+	return nil
+}
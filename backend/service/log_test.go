@@ -0,0 +1,72 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sbgayhub/chameleon/backend/config"
+)
+
+func writeLog(t *testing.T, dataDir, content string, flag int) {
+	t.Helper()
+	path := config.LogPath(dataDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.OpenFile(path, flag|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 跟随日志时只读取完整的行，日志被清空后从头读取
+func TestFollowLogs(t *testing.T) {
+	svc := &Service{dataDir: t.TempDir()}
+	writeLog(t, svc.dataDir, "a\nb\nc\npart", os.O_TRUNC)
+
+	lines, offset, err := svc.tailLogs(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(lines, []string{"b", "c"}) || offset != 6 {
+		t.Fatalf("tailLogs() = %q, %d, want [b c], 6", lines, offset)
+	}
+
+	writeLog(t, svc.dataDir, "ial\nd\n", os.O_APPEND)
+	lines, offset, err = svc.readLogsFrom(offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(lines, []string{"partial", "d"}) {
+		t.Fatalf("readLogsFrom() = %q, want [partial d]", lines)
+	}
+
+	if lines, _, _ := svc.readLogsFrom(offset); len(lines) != 0 {
+		t.Errorf("没有新日志时 readLogsFrom() = %q", lines)
+	}
+
+	writeLog(t, svc.dataDir, "e\n", os.O_TRUNC)
+	lines, offset, err = svc.readLogsFrom(offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(lines, []string{"e"}) || offset != 2 {
+		t.Errorf("清空后 readLogsFrom() = %q, %d, want [e], 2", lines, offset)
+	}
+}
+
+func TestLogsWithoutFile(t *testing.T) {
+	svc := &Service{dataDir: t.TempDir()}
+	if lines, err := svc.GetLogs(10); err != nil || len(lines) != 0 {
+		t.Errorf("GetLogs() = %q, %v, want 空", lines, err)
+	}
+	if err := svc.ClearLogs(); err != nil {
+		t.Errorf("ClearLogs() error = %v", err)
+	}
+}
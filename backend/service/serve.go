@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Serve 以无界面方式运行：启动管理接口和代理服务器，直到 ctx 结束后优雅退出
+func (svc *Service) Serve(ctx context.Context) error {
+	svc.Start(ctx)
+
+	if err := svc.StartProxy(); err != nil {
+		svc.Shutdown(context.Background())
+		return err
+	}
+	slog.Info("chameleon 已在无界面模式下运行", "mode", svc.ConfigMgr.GetConfig().Proxy.Mode)
+
+	<-ctx.Done()
+	slog.Info("收到退出信号，正在停止服务")
+	svc.Shutdown(context.Background())
+	return nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/config"
+	"github.com/sbgayhub/chameleon/backend/server"
+)
+
+// StartProxy 启动代理服务器
+func (svc *Service) StartProxy() error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if svc.running {
+		return fmt.Errorf("代理服务器已在运行")
+	}
+
+	// 检查代理管理器是否初始化
+	if svc.ChannelMgr == nil {
+		return fmt.Errorf("代理管理器未初始化")
+	}
+
+	// 检查统计管理器是否初始化
+	if svc.StatsMgr == nil {
+		return fmt.Errorf("统计管理器未初始化")
+	}
+
+	// 获取配置
+	config := svc.ConfigMgr.GetConfig()
+	if config == nil {
+		return fmt.Errorf("应用配置未初始化")
+	}
+
+	svc.Server = svc.newServer(config.Proxy)
+	svc.mode = config.Proxy.Mode
+	if err := svc.Server.Start(); err != nil {
+		return fmt.Errorf("启动代理服务器失败: %w", err)
+	}
+
+	svc.running = true
+	svc.startTime = time.Now()
+	svc.notify(true)
+
+	slog.Info("代理服务器启动成功", "mode", config.Proxy.Mode)
+	return nil
+}
+
+// newServer 按代理模式创建服务器
+func (svc *Service) newServer(proxy *config.ProxyConfig) server.Server {
+	switch proxy.Mode {
+	case "host":
+		return server.NewHostServer(proxy, svc.HostMgr, svc.ChannelMgr, svc.StatsMgr, svc.RecordMgr)
+	case "socks":
+		return server.NewSocksServer(proxy, svc.ChannelMgr, svc.StatsMgr, svc.RecordMgr)
+	case "gateway":
+		return server.NewGatewayServer(proxy, svc.KeyMgr, svc.ChannelMgr, svc.StatsMgr, svc.RecordMgr)
+	default:
+		return server.NewProxyServer(proxy, svc.ChannelMgr, svc.StatsMgr, svc.RecordMgr)
+	}
+}
+
+// applyProxyConfig 代理配置更新后应用到运行中的服务器，模式变化时切换服务器，否则由服务器自行重新监听
+func (svc *Service) applyProxyConfig(proxy *config.ProxyConfig) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if !svc.running || proxy == nil {
+		return
+	}
+	if proxy.Mode == svc.mode {
+		if err := svc.Server.UpdateConfig(proxy); err != nil {
+			slog.Error("应用代理配置失败", "error", err)
+		}
+		return
+	}
+
+	slog.Info("代理模式已更改，切换代理服务器", "old", svc.mode, "new", proxy.Mode)
+	if err := svc.Server.Stop(); err != nil {
+		slog.Error("停止代理服务器失败", "error", err)
+		return
+	}
+	svc.Server = svc.newServer(proxy)
+	svc.mode = proxy.Mode
+	if err := svc.Server.Start(); err != nil {
+		slog.Error("启动代理服务器失败", "mode", proxy.Mode, "error", err)
+		svc.running = false
+		svc.notify(false)
+	}
+}
+
+// watchConfig 监听代理配置变更，直到取消订阅
+func (svc *Service) watchConfig(updates <-chan *config.ProxyConfig) {
+	for proxy := range updates {
+		if proxy != nil {
+			svc.configureCertCache(proxy)
+		}
+		svc.applyProxyConfig(proxy)
+	}
+}
+
+// StopProxy 停止代理服务器
+func (svc *Service) StopProxy() error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if !svc.running {
+		return fmt.Errorf("代理服务器未运行")
+	}
+
+	if err := svc.Server.Stop(); err != nil {
+		return fmt.Errorf("停止代理服务器失败: %w", err)
+	}
+
+	svc.running = false
+	svc.notify(false)
+	slog.Info("代理服务器已停止")
+	return nil
+}
+
+// GetProxyStatus 获取代理状态
+func (svc *Service) GetProxyStatus() *ProxyStatus {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	// 获取配置信息，使用默认值
+	var port uint16 = 8080
+	var mode string = "http"
+	config := svc.ConfigMgr.GetConfig()
+	if config != nil {
+		port = config.Proxy.Port
+		mode = config.Proxy.Mode
+	}
+
+	status := &ProxyStatus{
+		IsRunning:         svc.running,
+		Port:              port,
+		Mode:              mode,
+		ActiveConnections: 0,
+		TotalRequests:     0,
+	}
+
+	// 安全检查：如果统计管理器存在，获取总请求数
+	if svc.StatsMgr != nil {
+		status.TotalRequests = svc.StatsMgr.GetTotalRequests()
+	}
+
+	if svc.running && !svc.startTime.IsZero() {
+		status.StartTime = svc.startTime.Unix()
+		status.Uptime = int64(time.Since(svc.startTime).Seconds())
+	}
+
+	return status
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/admin"
+	"github.com/sbgayhub/chameleon/backend/apikey"
+	"github.com/sbgayhub/chameleon/backend/certificate"
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/config"
+	"github.com/sbgayhub/chameleon/backend/convert/anthropic"
+	"github.com/sbgayhub/chameleon/backend/convert/openai"
+	"github.com/sbgayhub/chameleon/backend/host"
+	"github.com/sbgayhub/chameleon/backend/record"
+	"github.com/sbgayhub/chameleon/backend/server"
+	"github.com/sbgayhub/chameleon/backend/statistics"
+	"github.com/sbgayhub/chameleon/backend/telemetry"
+	"github.com/sbgayhub/chameleon/backend/transport"
+)
+
+// ProxyStatus 代理服务状态信息
+type ProxyStatus struct {
+	IsRunning         bool   `json:"isRunning"`
+	StartTime         int64  `json:"startTime"`
+	Uptime            int64  `json:"uptime"`
+	Port              uint16 `json:"port"`
+	ActiveConnections int    `json:"activeConnections"`
+	TotalRequests     int64  `json:"totalRequests"`
+	Mode              string `json:"mode"`
+}
+
+// Service 代理服务，管理代理服务器、管理接口及其依赖的各管理器，不依赖图形界面，
+// 图形界面和无界面模式（serve）共用
+type Service struct {
+	running   bool
+	startTime time.Time
+	mode      string // 运行中服务器的代理模式
+	dataDir   string // 应用数据目录
+	unwatch   func() // 停止监听配置变更
+	configErr error  // 启动时加载配置文件的错误
+
+	HostMgr      *host.Manager
+	ConfigMgr    *config.Manager
+	ChannelMgr   *channel.Manager
+	StatsMgr     *statistics.Manager
+	RecordMgr    *record.Manager
+	CertMgr      *certificate.CertManager
+	TelemetryMgr *telemetry.Manager
+	KeyMgr       *apikey.Manager
+
+	Server      server.Server
+	AdminServer *admin.Server
+
+	// OnProxyStatus 代理服务器启动或停止时调用，用于同步托盘等界面状态
+	OnProxyStatus func(running bool)
+
+	ctx context.Context
+	mu  sync.RWMutex
+}
+
+// DefaultDataDir 获取应用数据目录（exe同级的data文件夹）
+func DefaultDataDir() string {
+	exePath, err := os.Executable()
+	if err != nil {
+		// 如果获取失败，回退到用户目录
+		homeDir, _ := os.UserHomeDir()
+		if homeDir == "" {
+			homeDir = os.TempDir()
+		}
+		return filepath.Join(homeDir, "github.com/sbgayhub/chameleon")
+	}
+
+	// 获取可执行文件所在目录
+	dir := filepath.Join(filepath.Dir(exePath), "data")
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		_ = os.MkdirAll(dir, 0755)
+	}
+	return dir
+}
+
+// New 使用指定的数据目录创建代理服务
+func New(dataDir string) *Service {
+	// 初始化配置管理器
+	configMgr, configErr := config.NewManager(dataDir)
+	transport.Setup(configMgr)
+
+	// 初始化其他管理器
+	channelMgr := channel.NewManager(dataDir)
+	statsMgr := statistics.NewManager(dataDir)
+	recordMgr := record.NewManager(dataDir, configMgr)
+	certMgr := certificate.NewManager(dataDir)
+	hostMgr := host.NewManager(host.SystemPath(), dataDir)
+
+	// 清理上次异常退出时残留的 hosts 劫持记录，其他实例正在运行时劫持记录仍在使用，不做清理
+	if !statsMgr.Owner() {
+		slog.Info("数据目录正被其他实例使用，跳过清理 hosts 劫持记录", "dir", dataDir)
+	} else if _, err := hostMgr.Cleanup(); err != nil {
+		slog.Warn("清理 hosts 劫持记录失败", "error", err)
+	}
+
+	// 加载代理配置
+	if err := channelMgr.LoadFromFile(); err != nil {
+		slog.Warn("加载代理配置失败", "error", err)
+	}
+
+	// 注册转换器
+	anthropic.RegistryOpenAIConverter()
+	anthropic.RegistryGeminiConverter()
+	anthropic.RegistryAnthropicConverter()
+	openai.RegistryOpenAIConverter()
+	openai.RegistryAnthropicConverter()
+
+	svc := &Service{
+		HostMgr:      hostMgr,
+		CertMgr:      certMgr,
+		ConfigMgr:    configMgr,
+		ChannelMgr:   channelMgr,
+		StatsMgr:     statsMgr,
+		RecordMgr:    recordMgr,
+		TelemetryMgr: telemetry.NewManager(configMgr, channelMgr),
+		KeyMgr:       apikey.NewManager(dataDir),
+		running:      false,
+		dataDir:      dataDir,
+		configErr:    configErr,
+		ctx:          context.Background(),
+	}
+
+	svc.configureCertCache(configMgr.GetConfig().Proxy)
+	svc.syncCertInstalled()
+
+	// 渠道配置文件被外部修改时重新加载，代理配置变更时应用到运行中的服务器
+	stopWatch := channelMgr.Watch(2 * time.Second)
+	updates, unsubscribe := configMgr.SubscribeProxy()
+	go svc.watchConfig(updates)
+	svc.unwatch = func() {
+		stopWatch()
+		unsubscribe()
+	}
+	return svc
+}
+
+// Start 启动链路追踪和管理接口，代理服务器由 StartProxy 单独启动
+func (svc *Service) Start(ctx context.Context) {
+	svc.ctx = ctx
+	svc.startAdmin()
+}
+
+// Shutdown 停止代理服务器和管理接口，并保存统计数据
+func (svc *Service) Shutdown(ctx context.Context) {
+	svc.mu.RLock()
+	running := svc.running
+	svc.mu.RUnlock()
+	if running {
+		if err := svc.StopProxy(); err != nil {
+			slog.Warn("停止代理服务器失败", "error", err)
+		}
+	}
+
+	svc.stopAdmin(ctx)
+	svc.unwatch()
+
+	// 统计数据落盘
+	if err := svc.StatsMgr.Close(); err != nil {
+		slog.Warn("保存统计数据失败", "error", err)
+	}
+	if err := svc.RecordMgr.Close(); err != nil {
+		slog.Warn("关闭请求记录数据库失败", "error", err)
+	}
+}
+
+// GetConfigError 返回启动时加载配置文件的错误，配置正常时返回空字符串
+func (svc *Service) GetConfigError() string {
+	if svc.configErr == nil {
+		return ""
+	}
+	return svc.configErr.Error()
+}
+
+// notify 通知代理服务器运行状态变化
+func (svc *Service) notify(running bool) {
+	if svc.OnProxyStatus != nil {
+		svc.OnProxyStatus(running)
+	}
+}
@@ -0,0 +1,176 @@
+package statistics
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Event 单次请求的统计事件，追加写入事件日志
+type Event struct {
+	Seq          uint64    `json:"seq"`               // 事件序号，单调递增
+	Time         time.Time `json:"time"`              // 发生时间
+	ChannelName  string    `json:"channel_name"`      // 渠道名称
+	Key          string    `json:"key,omitempty"`     // 网关客户端密钥ID，非空时只计入密钥统计
+	Success      bool      `json:"success"`           // 是否成功
+	Aborted      bool      `json:"aborted,omitempty"` // 客户端中途取消，不计入成功或失败
+	InputTokens  uint64    `json:"input_tokens"`      // 输入token数
+	OutputTokens uint64    `json:"output_tokens"`     // 输出token数
+}
+
+// journal 追加写的统计事件日志，每行一个JSON事件。
+// 落盘时先将当前日志轮换为 sealed 文件，快照写入后再删除，写快照期间新事件写入新的日志文件
+type journal struct {
+	path     string
+	file     *os.File
+	readOnly bool // 未持有数据目录锁时不写入也不轮换日志
+}
+
+func newJournal(path string) *journal {
+	return &journal{path: path}
+}
+
+// sealedPath 已轮换、等待快照落盘后删除的日志
+func (j *journal) sealedPath() string {
+	return j.path + ".sealed"
+}
+
+// open 以追加模式打开日志文件
+func (j *journal) open() error {
+	if j.file != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开统计事件日志失败: %w", err)
+	}
+	j.file = file
+	return nil
+}
+
+// append 追加一条事件，单次write保证整行写入
+func (j *journal) append(e Event) error {
+	if j.readOnly {
+		return nil
+	}
+	if err := j.open(); err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Write(append(line, '\n'))
+	return err
+}
+
+// replay 依次读取轮换的日志和当前日志中的事件，损坏的行会被忽略
+func (j *journal) replay(apply func(Event)) error {
+	for _, path := range []string{j.sealedPath(), j.path} {
+		if err := replayFile(path, apply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayFile 读取单个日志文件中的事件
+func replayFile(path string, apply func(Event)) error {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			slog.Warn("跳过损坏的统计事件", "error", err)
+			continue
+		}
+		apply(e)
+	}
+	return scanner.Err()
+}
+
+// rotate 将当前日志改名为 sealed 文件，之后的事件写入新文件，调用方需持有统计锁以保证轮换前的事件都已计入内存。
+// 上次轮换的日志尚未删除（快照落盘失败）时不再轮换，其中的事件由下次成功落盘后一并删除
+func (j *journal) rotate() error {
+	if j.readOnly {
+		return nil
+	}
+	if _, err := os.Stat(j.sealedPath()); err == nil {
+		return nil
+	}
+	if err := j.close(); err != nil {
+		return err
+	}
+	if err := os.Rename(j.path, j.sealedPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// removeSealed 删除已包含在快照中的轮换日志
+func (j *journal) removeSealed() error {
+	if j.readOnly {
+		return nil
+	}
+	if err := os.Remove(j.sealedPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// close 关闭日志文件，下次追加时重新打开
+func (j *journal) close() error {
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// writeFileAtomic 先写临时文件并同步到磁盘，再重命名覆盖目标文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
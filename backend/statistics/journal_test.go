@@ -0,0 +1,129 @@
+package statistics
+
+import (
+	"os"
+	"testing"
+)
+
+// usage 渠道 ch 的请求数、token 数和密钥 key 的 token 数
+func usage(m *Manager) (requests, tokens, keyTokens uint64) {
+	if stats := m.GetAllStatistics()["ch"]; stats != nil {
+		requests, tokens = stats.RequestCount, stats.InputToken+stats.OutputToken
+	}
+	return requests, tokens, m.KeyTokens("key")
+}
+
+func record(m *Manager, n int) {
+	for range n {
+		m.UpdateStatistics("ch", 1, 2, true)
+		m.UpdateKeyStatistics("key", 1, 2, true, false)
+	}
+}
+
+func TestJournalRecovery(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, m *Manager)
+	}{
+		{
+			name: "crash before flush",
+			run:  func(t *testing.T, m *Manager) { record(m, 3) },
+		},
+		{
+			name: "crash after flush",
+			run: func(t *testing.T, m *Manager) {
+				record(m, 2)
+				if err := m.Flush(); err != nil {
+					t.Fatal(err)
+				}
+				record(m, 1)
+			},
+		},
+		{
+			name: "crash after rotate before snapshot",
+			run: func(t *testing.T, m *Manager) {
+				record(m, 2)
+				if err := m.journal.rotate(); err != nil {
+					t.Fatal(err)
+				}
+				record(m, 1)
+			},
+		},
+		{
+			name: "crash after snapshot before removing sealed journal",
+			run: func(t *testing.T, m *Manager) {
+				record(m, 2)
+				if err := m.journal.rotate(); err != nil {
+					t.Fatal(err)
+				}
+				record(m, 1)
+				for _, save := range []func() error{m.Save, m.SaveDaily, m.SaveKeys} {
+					if err := save(); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := newTestManager(t, dir)
+			tt.run(t, m)
+			crash(m)
+
+			requests, tokens, keyTokens := usage(newTestManager(t, dir))
+			if requests != 3 || tokens != 9 || keyTokens != 9 {
+				t.Fatalf("recovered requests=%d tokens=%d keyTokens=%d, want 3 9 9", requests, tokens, keyTokens)
+			}
+		})
+	}
+}
+
+func TestFlushRotatesJournal(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestManager(t, dir)
+	record(m, 2)
+	if err := m.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{m.journal.path, m.journal.sealedPath()} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed after flush: %v", path, err)
+		}
+	}
+
+	// 落盘后新事件写入新的日志，其中只包含未落盘的事件
+	record(m, 1)
+	count := 0
+	if err := m.journal.replay(func(Event) { count++ }); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("journal events = %d, want 2", count)
+	}
+}
+
+func TestSecondProcessIsReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestManager(t, dir)
+	if !m.Owner() {
+		t.Fatal("Owner() = false while manager holds the lock")
+	}
+	if !InUse(dir) {
+		t.Fatal("InUse() = false while manager holds the lock")
+	}
+	if _, err := acquireLock(dir); err != ErrInUse {
+		t.Fatalf("acquireLock() = %v, want ErrInUse", err)
+	}
+	if second := (&Manager{}); second.Owner() {
+		t.Error("未持有锁的管理器 Owner() = true")
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if InUse(dir) {
+		t.Fatal("InUse() = true after manager closed")
+	}
+}
@@ -0,0 +1,39 @@
+package statistics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrInUse 统计数据正被其他进程（界面或 serve）使用
+var ErrInUse = errors.New("统计数据正被其他 Chameleon 实例使用")
+
+// lockName 数据目录锁文件，持有锁的进程负责写入统计数据，进程退出时由系统释放
+const lockName = "stats.lock"
+
+// acquireLock 获取数据目录的统计数据锁，已被其他进程持有时返回 ErrInUse
+func acquireLock(dataDir string) (*os.File, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(dataDir, lockName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// InUse 判断数据目录的统计数据是否正被其他进程使用，此时不应直接修改统计文件
+func InUse(dataDir string) bool {
+	file, err := acquireLock(dataDir)
+	if err != nil {
+		return errors.Is(err, ErrInUse)
+	}
+	_ = file.Close()
+	return false
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package statistics
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile 对文件加非阻塞排他锁
+func lockFile(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrInUse
+	}
+	return err
+}
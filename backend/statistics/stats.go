@@ -1,19 +1,26 @@
 package statistics
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// flushInterval 内存统计数据定期落盘的间隔
+const flushInterval = 30 * time.Second
+
 // Statistics 统计数据
 type Statistics struct {
 	ChannelName  string    `json:"channel_name"`  // 渠道名称
 	RequestCount uint64    `json:"request_count"` // 请求次数
 	SuccessCount uint64    `json:"success_count"` // 成功次数
 	FailureCount uint64    `json:"failure_count"` // 失败次数
+	AbortedCount uint64    `json:"aborted_count"` // 客户端取消次数
 	InputToken   uint64    `json:"input_token"`   // 输入（请求）token数
 	OutputToken  uint64    `json:"output_token"`  // 输出（响应）token数
 	LastUsed     time.Time `json:"last_used"`     // 最后使用时间
@@ -25,17 +32,36 @@ type DailyStats struct {
 	RequestCount uint64 `json:"request_count"` // 请求次数
 	SuccessCount uint64 `json:"success_count"` // 成功次数
 	FailureCount uint64 `json:"failure_count"` // 失败次数
+	AbortedCount uint64 `json:"aborted_count"` // 客户端取消次数
 	InputToken   uint64 `json:"input_token"`   // 输入token数
 	OutputToken  uint64 `json:"output_token"`  // 输出token数
 }
 
+// snapshot 统计快照文件格式，Seq 为快照包含的最后一条事件序号
+type snapshot[T any] struct {
+	Seq  uint64        `json:"seq"`
+	Data map[string]*T `json:"data"`
+}
+
 // Manager 统计管理器
 type Manager struct {
 	dataPath    string
 	dailyPath   string
+	keyPath     string
 	data        map[string]*Statistics // key: channelGroup/channelName
 	dailyStats  map[string]*DailyStats // key: date
+	keyStats    map[string]*Statistics // key: 网关客户端密钥ID
 	currentDate string
+	journal     *journal // 追加写事件日志，用于崩溃后重建统计数据
+	seq         uint64   // 最后一条事件的序号
+	dataSeq     uint64   // stats.json 已持久化的事件序号
+	dailySeq    uint64   // daily.json 已持久化的事件序号
+	keySeq      uint64   // key_stats.json 已持久化的事件序号
+	dirty       bool     // 是否存在未落盘的数据
+	lock        *os.File // 数据目录锁，为空时其他进程正在使用统计数据，本进程只读
+	stop        chan struct{}
+	done        chan struct{}
+	flushMu     sync.Mutex // 串行执行落盘，不阻塞统计更新
 	mutex       sync.RWMutex
 }
 
@@ -48,12 +74,24 @@ var (
 func NewManager(dataDir string) *Manager {
 	once.Do(func() {
 		manager = &Manager{
-			dataPath:    dataDir + "/stats.json",
-			dailyPath:   dataDir + "/daily.json",
+			dataPath:    filepath.Join(dataDir, "stats.json"),
+			dailyPath:   filepath.Join(dataDir, "daily.json"),
+			keyPath:     filepath.Join(dataDir, "key_stats.json"),
 			data:        make(map[string]*Statistics),
 			dailyStats:  make(map[string]*DailyStats),
+			keyStats:    make(map[string]*Statistics),
 			currentDate: time.Now().Format("2006-01-02"),
+			journal:     newJournal(filepath.Join(dataDir, "stats.journal")),
+			stop:        make(chan struct{}),
+			done:        make(chan struct{}),
 		}
+		// 同一数据目录只允许一个进程写入统计数据，否则只读，避免互相覆盖快照或轮换对方的事件日志
+		lock, err := acquireLock(dataDir)
+		if err != nil {
+			slog.Warn("无法写入统计数据，本进程只读取统计数据", "dir", dataDir, "error", err)
+			manager.journal.readOnly = true
+		}
+		manager.lock = lock
 		// 启动时加载数据
 		if err := manager.Load(); err != nil {
 			slog.Warn("加载统计数据失败，使用空数据", "error", err)
@@ -61,6 +99,14 @@ func NewManager(dataDir string) *Manager {
 		if err := manager.LoadDaily(); err != nil {
 			slog.Warn("加载每日统计失败，使用空数据", "error", err)
 		}
+		if err := manager.LoadKeys(); err != nil {
+			slog.Warn("加载密钥统计失败，使用空数据", "error", err)
+		}
+		// 回放上次未落盘的事件
+		if err := manager.replay(); err != nil {
+			slog.Warn("回放统计事件日志失败", "error", err)
+		}
+		go manager.flushLoop()
 	})
 
 	return manager
@@ -85,7 +131,13 @@ func (m *Manager) Load() error {
 		return nil
 	}
 
-	return json.Unmarshal(data, &m.data)
+	seq, err := decodeSnapshot(data, &m.data)
+	if err != nil {
+		return err
+	}
+	m.dataSeq = seq
+	m.seq = max(m.seq, seq)
+	return nil
 }
 
 // LoadDaily 加载每日统计
@@ -106,84 +158,378 @@ func (m *Manager) LoadDaily() error {
 		return nil
 	}
 
-	return json.Unmarshal(data, &m.dailyStats)
+	seq, err := decodeSnapshot(data, &m.dailyStats)
+	if err != nil {
+		return err
+	}
+	m.dailySeq = seq
+	m.seq = max(m.seq, seq)
+	return nil
 }
 
-// Save 保存统计数据
-func (m *Manager) Save() error {
+// LoadKeys 加载网关客户端密钥统计
+func (m *Manager) LoadKeys() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	data, err := json.MarshalIndent(m.data, "", "  ")
+	data, err := os.ReadFile(m.keyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	seq, err := decodeSnapshot(data, &m.keyStats)
+	if err != nil {
+		return err
+	}
+	m.keySeq = seq
+	m.seq = max(m.seq, seq)
+	return nil
+}
+
+// decodeSnapshot 解析快照文件，兼容旧版本直接保存map的格式
+func decodeSnapshot[T any](data []byte, target *map[string]*T) (uint64, error) {
+	var snap snapshot[T]
+	if err := json.Unmarshal(data, &snap); err == nil && snap.Data != nil {
+		*target = snap.Data
+		return snap.Seq, nil
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// Save 保存统计数据
+func (m *Manager) Save() error {
+	m.mutex.RLock()
+	data, err := json.MarshalIndent(snapshot[Statistics]{Seq: m.seq, Data: m.data}, "", "  ")
+	seq := m.seq
+	m.mutex.RUnlock()
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(m.dataPath, data, 0644)
+	if err := writeFileAtomic(m.dataPath, data, 0644); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	m.dataSeq = seq
+	m.mutex.Unlock()
+	return nil
 }
 
 // SaveDaily 保存每日统计
 func (m *Manager) SaveDaily() error {
+	m.mutex.RLock()
+	data, err := json.MarshalIndent(snapshot[DailyStats]{Seq: m.seq, Data: m.dailyStats}, "", "  ")
+	seq := m.seq
+	m.mutex.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(m.dailyPath, data, 0644); err != nil {
+		return err
+	}
+
 	m.mutex.Lock()
-	defer m.mutex.Unlock()
+	m.dailySeq = seq
+	m.mutex.Unlock()
+	return nil
+}
 
-	data, err := json.MarshalIndent(m.dailyStats, "", "  ")
+// SaveKeys 保存网关客户端密钥统计
+func (m *Manager) SaveKeys() error {
+	m.mutex.RLock()
+	data, err := json.MarshalIndent(snapshot[Statistics]{Seq: m.seq, Data: m.keyStats}, "", "  ")
+	seq := m.seq
+	m.mutex.RUnlock()
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(m.dailyPath, data, 0644)
+	if err := writeFileAtomic(m.keyPath, data, 0644); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	m.keySeq = seq
+	m.mutex.Unlock()
+	return nil
+}
+
+// Flush 将内存中的统计数据写入磁盘。持有统计锁时只轮换事件日志，
+// 快照写入和旧日志删除在锁外进行，避免落盘期间阻塞统计更新
+func (m *Manager) Flush() error {
+	if m.lock == nil {
+		return nil
+	}
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+
+	m.mutex.Lock()
+	if !m.dirty {
+		m.mutex.Unlock()
+		return nil
+	}
+	m.dirty = false
+	// 轮换前的事件都已计入内存，之后写入的快照一定包含这些事件
+	if err := m.journal.rotate(); err != nil {
+		slog.Warn("轮换统计事件日志失败", "error", err)
+	}
+	m.mutex.Unlock()
+
+	if err := m.Save(); err != nil {
+		m.markDirty()
+		return err
+	}
+	if err := m.SaveDaily(); err != nil {
+		m.markDirty()
+		return err
+	}
+	if err := m.SaveKeys(); err != nil {
+		m.markDirty()
+		return err
+	}
+
+	// 轮换出的事件都已包含在快照中
+	return m.journal.removeSealed()
+}
+
+// Owner 本进程是否持有数据目录锁，其他实例（界面或 serve）正在使用同一数据目录时为 false
+func (m *Manager) Owner() bool {
+	return m.lock != nil
+}
+
+// Close 停止定期落盘并执行最后一次落盘
+func (m *Manager) Close() error {
+	select {
+	case <-m.stop:
+		return nil
+	default:
+		close(m.stop)
+	}
+	<-m.done
+
+	err := m.Flush()
+	if closeErr := m.journal.close(); err == nil {
+		err = closeErr
+	}
+	if m.lock != nil {
+		_ = m.lock.Close()
+	}
+	slog.Info("统计数据已保存")
+	return err
+}
+
+func (m *Manager) markDirty() {
+	m.mutex.Lock()
+	m.dirty = true
+	m.mutex.Unlock()
+}
+
+// flushLoop 定期将统计数据落盘
+func (m *Manager) flushLoop() {
+	defer close(m.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Flush(); err != nil {
+				slog.Warn("统计数据落盘失败", "error", err)
+			}
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// replay 回放事件日志中尚未包含在快照内的事件
+func (m *Manager) replay() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	count := 0
+	err := m.journal.replay(func(e Event) {
+		switch {
+		case e.Key != "":
+			if e.Seq > m.keySeq {
+				m.applyKey(e)
+			}
+		default:
+			if e.Seq > m.dataSeq {
+				m.applyStatistics(e)
+			}
+			if e.Seq > m.dailySeq {
+				m.applyDaily(e)
+			}
+		}
+		if e.Seq > min(m.dataSeq, m.dailySeq, m.keySeq) {
+			count++
+			m.dirty = true
+		}
+		m.seq = max(m.seq, e.Seq)
+	})
+	if count > 0 {
+		slog.Info("已从事件日志恢复统计数据", "events", count)
+	}
+	return err
 }
 
 // UpdateStatistics 更新统计数据
 func (m *Manager) UpdateStatistics(channelName string, inputTokens, outputTokens uint64, success bool) {
 	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.seq++
+	event := Event{
+		Seq:          m.seq,
+		Time:         time.Now(),
+		ChannelName:  channelName,
+		Success:      success,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+	}
+	m.applyStatistics(event)
+	m.applyDaily(event)
+	m.dirty = true
+
+	// 只追加事件日志，快照由后台定期落盘
+	if err := m.journal.append(event); err != nil {
+		slog.Warn("写入统计事件日志失败", "error", err)
+	}
+}
+
+// UpdateAborted 记录客户端中途取消的请求，已消耗的token仍计入用量
+func (m *Manager) UpdateAborted(channelName string, inputTokens, outputTokens uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.seq++
+	event := Event{
+		Seq:          m.seq,
+		Time:         time.Now(),
+		ChannelName:  channelName,
+		Aborted:      true,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+	}
+	m.applyStatistics(event)
+	m.applyDaily(event)
+	m.dirty = true
+
+	if err := m.journal.append(event); err != nil {
+		slog.Warn("写入统计事件日志失败", "error", err)
+	}
+}
+
+// UpdateKeyStatistics 更新网关客户端密钥的用量统计
+func (m *Manager) UpdateKeyStatistics(keyID string, inputTokens, outputTokens uint64, success, aborted bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.seq++
+	event := Event{
+		Seq:          m.seq,
+		Time:         time.Now(),
+		Key:          keyID,
+		Success:      success,
+		Aborted:      aborted,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+	}
+	m.applyKey(event)
+	m.dirty = true
+
+	if err := m.journal.append(event); err != nil {
+		slog.Warn("写入统计事件日志失败", "error", err)
+	}
+}
 
-	stats, exists := m.data[channelName]
+// applyKey 将事件累加到密钥统计
+func (m *Manager) applyKey(e Event) {
+	stats, exists := m.keyStats[e.Key]
+	if !exists {
+		stats = &Statistics{ChannelName: e.Key}
+		m.keyStats[e.Key] = stats
+	}
+
+	stats.RequestCount++
+	stats.InputToken += e.InputTokens
+	stats.OutputToken += e.OutputTokens
+	if e.Time.After(stats.LastUsed) {
+		stats.LastUsed = e.Time
+	}
+	switch {
+	case e.Aborted:
+		stats.AbortedCount++
+	case e.Success:
+		stats.SuccessCount++
+	default:
+		stats.FailureCount++
+	}
+}
+
+// applyStatistics 将事件累加到渠道统计
+func (m *Manager) applyStatistics(e Event) {
+	stats, exists := m.data[e.ChannelName]
 	if !exists {
 		stats = &Statistics{
-			ChannelName: channelName,
+			ChannelName: e.ChannelName,
 		}
-		m.data[channelName] = stats
+		m.data[e.ChannelName] = stats
 	}
 
 	stats.RequestCount++
-	stats.InputToken += inputTokens
-	stats.OutputToken += outputTokens
-	stats.LastUsed = time.Now()
+	stats.InputToken += e.InputTokens
+	stats.OutputToken += e.OutputTokens
+	if e.Time.After(stats.LastUsed) {
+		stats.LastUsed = e.Time
+	}
 
-	if success {
+	switch {
+	case e.Aborted:
+		stats.AbortedCount++
+	case e.Success:
 		stats.SuccessCount++
-	} else {
+	default:
 		stats.FailureCount++
 	}
+}
 
-	// 更新每日统计
-	today := time.Now().Format("2006-01-02")
-	if today != m.currentDate {
-		m.currentDate = today
+// applyDaily 将事件累加到每日统计
+func (m *Manager) applyDaily(e Event) {
+	day := e.Time.Format("2006-01-02")
+	if day > m.currentDate {
+		m.currentDate = day
 	}
 
-	dailyStats, exists := m.dailyStats[today]
+	dailyStats, exists := m.dailyStats[day]
 	if !exists {
-		dailyStats = &DailyStats{Date: today}
-		m.dailyStats[today] = dailyStats
+		dailyStats = &DailyStats{Date: day}
+		m.dailyStats[day] = dailyStats
 	}
 
 	dailyStats.RequestCount++
-	dailyStats.InputToken += inputTokens
-	dailyStats.OutputToken += outputTokens
-	if success {
+	dailyStats.InputToken += e.InputTokens
+	dailyStats.OutputToken += e.OutputTokens
+	switch {
+	case e.Aborted:
+		dailyStats.AbortedCount++
+	case e.Success:
 		dailyStats.SuccessCount++
-	} else {
+	default:
 		dailyStats.FailureCount++
 	}
-
-	m.mutex.Unlock()
-
-	// 在锁外保存数据
-	_ = m.Save()
-	_ = m.SaveDaily()
 }
 
 // GetAllStatistics 获取所有统计数据
@@ -191,23 +537,27 @@ func (m *Manager) GetAllStatistics() map[string]*Statistics {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
-	// 返回副本
+	// 返回副本，避免调用方读取时与更新产生数据竞争
 	result := make(map[string]*Statistics)
 	for k, v := range m.data {
-		result[k] = v
+		copied := *v
+		result[k] = &copied
 	}
 	return result
 }
 
-// ResetAllStatistics 重置所有统计数据
+// ResetAllStatistics 重置渠道统计和每日统计，密钥用量用于核算 token 预算，不随统计重置
 func (m *Manager) ResetAllStatistics() {
 	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
 	m.data = make(map[string]*Statistics)
 	m.dailyStats = make(map[string]*DailyStats)
-	_ = m.Save()
-	_ = m.SaveDaily()
+	m.dirty = true
+	m.mutex.Unlock()
+
+	// 立即落盘，避免重启后从旧快照恢复
+	if err := m.Flush(); err != nil {
+		slog.Warn("统计数据落盘失败", "error", err)
+	}
 }
 
 // GetDailyStatistics 获取每日统计
@@ -217,11 +567,36 @@ func (m *Manager) GetDailyStatistics() map[string]*DailyStats {
 
 	result := make(map[string]*DailyStats)
 	for k, v := range m.dailyStats {
-		result[k] = v
+		copied := *v
+		result[k] = &copied
 	}
 	return result
 }
 
+// GetKeyStatistics 获取网关客户端密钥的用量统计，key 为密钥ID
+func (m *Manager) GetKeyStatistics() map[string]*Statistics {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result := make(map[string]*Statistics)
+	for k, v := range m.keyStats {
+		copied := *v
+		result[k] = &copied
+	}
+	return result
+}
+
+// KeyTokens 获取网关客户端密钥已使用的token总数
+func (m *Manager) KeyTokens(keyID string) uint64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if stats, ok := m.keyStats[keyID]; ok {
+		return stats.InputToken + stats.OutputToken
+	}
+	return 0
+}
+
 // GetTotalRequests 获取总请求数
 func (m *Manager) GetTotalRequests() int64 {
 	m.mutex.RLock()
@@ -247,6 +622,7 @@ func (m *Manager) GetTotalStatistics() *Statistics {
 		totalStats.RequestCount += stats.RequestCount
 		totalStats.SuccessCount += stats.SuccessCount
 		totalStats.FailureCount += stats.FailureCount
+		totalStats.AbortedCount += stats.AbortedCount
 		totalStats.InputToken += stats.InputToken
 		totalStats.OutputToken += stats.OutputToken
 
@@ -258,6 +634,57 @@ func (m *Manager) GetTotalStatistics() *Statistics {
 	return totalStats
 }
 
+// UpdateStatistics 更新渠道统计，未创建统计管理器时（如命令行测试渠道）忽略
 func UpdateStatistics(name string, success bool, input, output uint64) {
+	if manager == nil {
+		return
+	}
 	manager.UpdateStatistics(name, input, output, success)
 }
+
+// UpdateAborted 记录客户端取消的请求，未创建统计管理器时忽略
+func UpdateAborted(name string, input, output uint64) {
+	if manager == nil {
+		return
+	}
+	manager.UpdateAborted(name, input, output)
+}
+
+// excludeKey 标记请求不计入统计的 context 键
+type excludeKey struct{}
+
+// Exclude 返回不计入统计的 context，重放等调试请求使用，避免影响实际用量
+func Exclude(ctx context.Context) context.Context {
+	return context.WithValue(ctx, excludeKey{}, true)
+}
+
+// Excluded 判断 context 是否被 Exclude 标记
+func Excluded(ctx context.Context) bool {
+	excluded, _ := ctx.Value(excludeKey{}).(bool)
+	return excluded
+}
+
+// countedKey 记录请求是否已计入统计的 context 键
+type countedKey struct{}
+
+// Track 返回跟踪计数状态的 context，请求结束时据此判断响应是否已计入统计，避免重复计数
+func Track(ctx context.Context) context.Context {
+	return context.WithValue(ctx, countedKey{}, new(atomic.Bool))
+}
+
+// Counted 判断 Track 跟踪的请求是否已通过 UpdateStatisticsContext 计入统计
+func Counted(ctx context.Context) bool {
+	counted, _ := ctx.Value(countedKey{}).(*atomic.Bool)
+	return counted != nil && counted.Load()
+}
+
+// UpdateStatisticsContext 同 UpdateStatistics，ctx 被 Exclude 标记时忽略，转换器传入请求的 context
+func UpdateStatisticsContext(ctx context.Context, name string, success bool, input, output uint64) {
+	if Excluded(ctx) {
+		return
+	}
+	if counted, _ := ctx.Value(countedKey{}).(*atomic.Bool); counted != nil {
+		counted.Store(true)
+	}
+	UpdateStatistics(name, success, input, output)
+}
@@ -0,0 +1,131 @@
+package statistics
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestManager 在临时目录创建统计管理器并设置为包级管理器，不启动后台落盘
+func newTestManager(t *testing.T, dir string) *Manager {
+	t.Helper()
+	lock, err := acquireLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Manager{
+		dataPath:    filepath.Join(dir, "stats.json"),
+		dailyPath:   filepath.Join(dir, "daily.json"),
+		keyPath:     filepath.Join(dir, "key_stats.json"),
+		data:        make(map[string]*Statistics),
+		dailyStats:  make(map[string]*DailyStats),
+		keyStats:    make(map[string]*Statistics),
+		currentDate: time.Now().Format("2006-01-02"),
+		journal:     newJournal(filepath.Join(dir, "stats.journal")),
+		lock:        lock,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	close(m.done)
+	for _, load := range []func() error{m.Load, m.LoadDaily, m.LoadKeys, m.replay} {
+		if err := load(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous := manager
+	manager = m
+	t.Cleanup(func() {
+		manager = previous
+		_ = m.Close()
+	})
+	return m
+}
+
+// crash 模拟进程异常退出：不落盘，直接释放文件和锁
+func crash(m *Manager) {
+	close(m.stop)
+	_ = m.journal.close()
+	_ = m.lock.Close()
+}
+
+func TestUpdateStatisticsContextExcluded(t *testing.T) {
+	m := newTestManager(t, t.TempDir())
+
+	UpdateStatisticsContext(Exclude(context.Background()), "replay", true, 10, 20)
+	if stats := m.GetAllStatistics(); len(stats) != 0 {
+		t.Fatalf("excluded request counted: %+v", stats["replay"])
+	}
+
+	UpdateStatisticsContext(context.Background(), "live", true, 10, 20)
+	stats := m.GetAllStatistics()["live"]
+	if stats == nil || stats.RequestCount != 1 || stats.InputToken != 10 || stats.OutputToken != 20 {
+		t.Fatalf("live stats = %+v", stats)
+	}
+}
+
+func TestTrackCounted(t *testing.T) {
+	newTestManager(t, t.TempDir())
+
+	if Counted(context.Background()) {
+		t.Fatal("untracked context reported as counted")
+	}
+	ctx := Track(context.Background())
+	if Counted(ctx) {
+		t.Fatal("tracked context counted before update")
+	}
+
+	// 重放请求不计入统计，也不应标记为已计数
+	UpdateStatisticsContext(Exclude(ctx), "replay", true, 1, 2)
+	if Counted(ctx) {
+		t.Fatal("excluded update marked context as counted")
+	}
+
+	// 派生的 context 共享计数状态，转换器使用的上游请求 context 派生自客户端请求
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	UpdateStatisticsContext(child, "live", true, 1, 2)
+	if !Counted(ctx) {
+		t.Fatal("update through derived context not visible on tracked context")
+	}
+}
+
+func TestGetStatisticsReturnsCopies(t *testing.T) {
+	m := newTestManager(t, t.TempDir())
+	m.UpdateStatistics("channel", 1, 2, true)
+
+	all := m.GetAllStatistics()["channel"]
+	daily := m.GetDailyStatistics()[m.currentDate]
+	m.UpdateStatistics("channel", 1, 2, true)
+	if all.RequestCount != 1 || daily.RequestCount != 1 {
+		t.Fatalf("returned statistics changed after update: all=%d daily=%d", all.RequestCount, daily.RequestCount)
+	}
+
+	all.RequestCount = 100
+	if got := m.GetAllStatistics()["channel"].RequestCount; got != 2 {
+		t.Fatalf("RequestCount = %d, want 2", got)
+	}
+}
+
+func TestResetKeepsKeyUsage(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestManager(t, dir)
+	m.UpdateStatistics("channel", 1, 2, true)
+	m.UpdateKeyStatistics("key", 100, 50, true, false)
+
+	m.ResetAllStatistics()
+	if len(m.GetAllStatistics()) != 0 || len(m.GetDailyStatistics()) != 0 {
+		t.Fatalf("channel statistics not reset: %v %v", m.GetAllStatistics(), m.GetDailyStatistics())
+	}
+	if got := m.KeyTokens("key"); got != 150 {
+		t.Fatalf("KeyTokens after reset = %d, want 150", got)
+	}
+
+	// 重新加载后预算用量仍然保留
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := newTestManager(t, dir).KeyTokens("key"); got != 150 {
+		t.Fatalf("KeyTokens after reload = %d, want 150", got)
+	}
+}
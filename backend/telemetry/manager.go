@@ -0,0 +1,138 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/config"
+	"github.com/sbgayhub/chameleon/backend/record"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/sbgayhub/chameleon"
+
+// Manager 监控管理器，负责注册渠道健康指标和初始化链路追踪
+type Manager struct {
+	configMgr *config.Manager
+	provider  *sdktrace.TracerProvider
+	mu        sync.Mutex
+}
+
+// NewManager 创建监控管理器
+func NewManager(configMgr *config.Manager, channelMgr *channel.Manager) *Manager {
+	registry.MustRegister(newHealthCollector(channelMgr))
+	return &Manager{configMgr: configMgr}
+}
+
+// config 获取监控配置
+func (m *Manager) config() *config.TelemetryConfig {
+	if m.configMgr == nil || m.configMgr.GetConfig().Telemetry == nil {
+		return &config.TelemetryConfig{}
+	}
+	return m.configMgr.GetConfig().Telemetry
+}
+
+// MetricsEnabled 是否提供 Prometheus 指标
+func (m *Manager) MetricsEnabled() bool {
+	return m.config().Metrics
+}
+
+// Start 按配置初始化链路追踪，未启用时使用全局的空实现
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := m.config()
+	if !cfg.Tracing || m.provider != nil {
+		return nil
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(strings.TrimPrefix(strings.TrimPrefix(cfg.OTLPEndpoint, "http://"), "https://"))}
+	if !strings.HasPrefix(cfg.OTLPEndpoint, "https://") {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return fmt.Errorf("创建 OTLP 导出器失败: %w", err)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "chameleon"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		res = resource.Default()
+	}
+
+	m.provider = sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(m.provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	slog.Info("链路追踪已启用", "endpoint", cfg.OTLPEndpoint)
+	return nil
+}
+
+// Shutdown 导出剩余的链路数据并关闭
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.provider == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := m.provider.Shutdown(ctx)
+	m.provider = nil
+	return err
+}
+
+// StartSpan 开始一个链路追踪片段，未启用链路追踪时返回空实现
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan 结束片段并记录错误
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Finish 请求结束时记录指标并结束根片段，用于 record.Trace.OnFinish
+func Finish(span trace.Span, ch *channel.Channel) func(*record.Record) {
+	return func(r *record.Record) {
+		Observe(r, ch)
+		span.SetAttributes(
+			attribute.String("chameleon.group", r.Group),
+			attribute.String("chameleon.channel", r.Channel),
+			attribute.String("chameleon.converter", r.Converter),
+			attribute.String("chameleon.model.original", r.OriginalModel),
+			attribute.String("chameleon.model.mapped", r.MappedModel),
+			attribute.Bool("chameleon.stream", r.Stream),
+			attribute.Int("http.response.status_code", r.Status),
+			attribute.Int64("chameleon.ttft_ms", r.TTFT),
+			attribute.Int64("chameleon.tokens.input", int64(r.InputTokens)),
+			attribute.Int64("chameleon.tokens.output", int64(r.OutputTokens)),
+		)
+		if !r.Success {
+			span.SetStatus(codes.Error, r.Error)
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,135 @@
+package telemetry
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/record"
+)
+
+const namespace = "chameleon"
+
+// labels 请求指标的标签
+var labels = []string{"group", "channel", "model"}
+
+// otherModel 渠道未知的模型使用的标签值，模型名由客户端传入，不加限制时标签数量没有上限
+const otherModel = "other"
+
+// latencyBuckets 请求耗时分布（秒），覆盖从快速响应到长时间流式输出
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300}
+
+var (
+	registry = prometheus.NewRegistry()
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "代理请求总数",
+	}, append(labels, "status", "success"))
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "代理请求总耗时",
+		Buckets:   latencyBuckets,
+	}, labels)
+
+	firstTokenDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "first_token_duration_seconds",
+		Help:      "代理请求首字耗时",
+		Buckets:   latencyBuckets,
+	}, labels)
+
+	tokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_total",
+		Help:      "token用量，type为input或output",
+	}, append(labels, "type"))
+
+	costTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cost_total",
+		Help:      "按渠道单价计算的累计费用",
+	}, labels)
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal, requestDuration, firstTokenDuration, tokensTotal, costTotal,
+	)
+}
+
+// Observe 记录一次代理请求的指标，在请求结束时调用，ch 为处理请求的渠道
+func Observe(r *record.Record, ch *channel.Channel) {
+	values := []string{r.Group, r.Channel, modelLabel(r, ch)}
+
+	requestsTotal.WithLabelValues(append(values, strconv.Itoa(r.Status), strconv.FormatBool(r.Success))...).Inc()
+	requestDuration.WithLabelValues(values...).Observe(float64(r.Latency) / 1000)
+	if r.TTFT > 0 {
+		firstTokenDuration.WithLabelValues(values...).Observe(float64(r.TTFT) / 1000)
+	}
+	if r.InputTokens > 0 {
+		tokensTotal.WithLabelValues(append(values, "input")...).Add(float64(r.InputTokens))
+	}
+	if r.OutputTokens > 0 {
+		tokensTotal.WithLabelValues(append(values, "output")...).Add(float64(r.OutputTokens))
+	}
+	if r.Cost > 0 {
+		costTotal.WithLabelValues(values...).Add(r.Cost)
+	}
+}
+
+// modelLabel 指标中的模型标签，只使用映射后且属于渠道已知模型的名称，其余归为 other
+func modelLabel(r *record.Record, ch *channel.Channel) string {
+	if ch != nil && ch.KnownModel(r.MappedModel) {
+		return r.MappedModel
+	}
+	return otherModel
+}
+
+// Handler Prometheus 指标接口
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// healthCollector 渠道健康状态采集器，每次抓取时读取渠道管理器的当前状态
+type healthCollector struct {
+	channelMgr *channel.Manager
+	status     *prometheus.Desc
+	enabled    *prometheus.Desc
+}
+
+func newHealthCollector(channelMgr *channel.Manager) *healthCollector {
+	return &healthCollector{
+		channelMgr: channelMgr,
+		status: prometheus.NewDesc(prometheus.BuildFQName(namespace, "channel", "status"),
+			"渠道状态：1正常，2异常，3不可用", []string{"group", "channel"}, nil),
+		enabled: prometheus.NewDesc(prometheus.BuildFQName(namespace, "channel", "enabled"),
+			"渠道是否启用（渠道组和渠道均启用时为1）", []string{"group", "channel"}, nil),
+	}
+}
+
+func (c *healthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.status
+	ch <- c.enabled
+}
+
+func (c *healthCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, group := range c.channelMgr.List() {
+		for _, node := range group.Channels {
+			enabled := 0.0
+			if group.Enabled && node.Enabled {
+				enabled = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, float64(node.Status), group.Endpoint, node.Name)
+			ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, enabled, group.Endpoint, node.Name)
+		}
+	}
+}
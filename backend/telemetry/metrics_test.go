@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sbgayhub/chameleon/backend/channel"
+	"github.com/sbgayhub/chameleon/backend/record"
+)
+
+func testChannel() *channel.Channel {
+	ch := &channel.Channel{Name: "test", TestModel: "gpt-4o-mini", Models: []string{"gpt-4o", "gpt-4.1"}, ModelMapper: channel.NewModelMapper()}
+	ch.ModelMapper.AddRule("claude-*", "o3")
+	return ch
+}
+
+func TestModelLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		mapped string
+		ch     *channel.Channel
+		want   string
+	}{
+		{name: "模型列表", mapped: "gpt-4o", ch: testChannel(), want: "gpt-4o"},
+		{name: "映射目标", mapped: "o3", ch: testChannel(), want: "o3"},
+		{name: "测试模型", mapped: "gpt-4o-mini", ch: testChannel(), want: "gpt-4o-mini"},
+		{name: "未知模型", mapped: "anything-goes", ch: testChannel(), want: otherModel},
+		{name: "没有模型", mapped: "", ch: testChannel(), want: otherModel},
+		{name: "没有渠道", mapped: "gpt-4o", want: otherModel},
+		{
+			name:   "未创建映射器时使用配置的映射",
+			mapped: "o3",
+			ch:     &channel.Channel{ModelMapping: map[string]string{"*": "o3"}},
+			want:   "o3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &record.Record{OriginalModel: "client-model", MappedModel: tt.mapped}
+			if got := modelLabel(r, tt.ch); got != tt.want {
+				t.Errorf("modelLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// seriesCount 指标当前的时间序列数量
+func seriesCount(t *testing.T, name string) int {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return len(family.GetMetric())
+		}
+	}
+	return 0
+}
+
+// 客户端传入任意模型名时标签数量不增长
+func TestObserveBoundsModelLabel(t *testing.T) {
+	ch := testChannel()
+	before := seriesCount(t, "chameleon_request_duration_seconds")
+	for i := range 100 {
+		model := fmt.Sprintf("random-%d", i)
+		Observe(&record.Record{Group: "bounded.example.com", Channel: ch.Name, OriginalModel: model, MappedModel: model, Latency: 10}, ch)
+	}
+	if got := seriesCount(t, "chameleon_request_duration_seconds") - before; got != 1 {
+		t.Errorf("新增时间序列 = %d, want 1", got)
+	}
+}
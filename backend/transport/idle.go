@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrStreamIdle 响应体超过空闲超时没有数据
+var ErrStreamIdle = errors.New("上游响应空闲超时")
+
+// idleRoundTripper 为响应体加上空闲超时，流式响应只要持续有数据就不会中断
+type idleRoundTripper struct {
+	next http.RoundTripper
+	idle time.Duration
+}
+
+func (t *idleRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.next.RoundTrip(request)
+	if err != nil || response.Body == nil || response.Body == http.NoBody {
+		return response, err
+	}
+	body := &idleBody{body: response.Body}
+	body.timer = time.AfterFunc(t.idle, body.expire)
+	body.idle = t.idle
+	response.Body = body
+	return response, nil
+}
+
+// idleBody 每次读取到数据后重置计时器，超时后关闭底层连接使阻塞的读取返回
+type idleBody struct {
+	body    io.ReadCloser
+	timer   *time.Timer
+	idle    time.Duration
+	mu      sync.Mutex
+	expired bool
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.expired {
+		return n, ErrStreamIdle
+	}
+	if n > 0 {
+		b.timer.Reset(b.idle)
+	}
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.timer.Stop()
+	return b.body.Close()
+}
+
+func (b *idleBody) expire() {
+	b.mu.Lock()
+	b.expired = true
+	b.mu.Unlock()
+	_ = b.body.Close()
+}
@@ -0,0 +1,159 @@
+package transport
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	xproxy "golang.org/x/net/proxy"
+)
+
+// proxyFuncs 按代理地址和 NO_PROXY 规则缓存的代理选择函数
+var proxyFuncs sync.Map // key: proxy + "\x00" + noProxy
+
+// proxyURL 获取访问目标地址使用的上游代理，nil 表示直连
+func proxyURL(channelProxy string, target *url.URL) (*url.URL, error) {
+	cfg := outbound()
+	raw := cmp.Or(channelProxy, cfg.Proxy)
+	if raw == "direct" {
+		return nil, nil
+	}
+	if raw == "" {
+		// 未配置时沿用系统代理环境变量 HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+		u, err := http.ProxyFromEnvironment(&http.Request{URL: target})
+		if u == nil || err != nil {
+			return u, err
+		}
+		raw = u.String()
+	}
+
+	noProxy := strings.Join(cfg.NoProxy, ",")
+	key := raw + "\x00" + noProxy
+	fn, ok := proxyFuncs.Load(key)
+	if !ok {
+		fn, _ = proxyFuncs.LoadOrStore(key, (&httpproxy.Config{HTTPProxy: raw, HTTPSProxy: raw, NoProxy: noProxy}).ProxyFunc())
+	}
+	return fn.(func(*url.URL) (*url.URL, error))(target)
+}
+
+// Dial 按全局出站配置建立到目标地址的 TCP 连接，用于直接转发的隧道
+func Dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := dialFunc(dialContext(&net.Dialer{Timeout: current().connect, KeepAlive: 30 * time.Second}))
+	proxy, err := proxyURL("", &url.URL{Scheme: "https", Host: addr})
+	if err != nil {
+		return nil, err
+	}
+	if proxy == nil {
+		return dial(ctx, network, addr)
+	}
+
+	switch proxy.Scheme {
+	case "socks5", "socks5h":
+		var auth *xproxy.Auth
+		if proxy.User != nil {
+			password, _ := proxy.User.Password()
+			auth = &xproxy.Auth{User: proxy.User.Username(), Password: password}
+		}
+		d, err := xproxy.SOCKS5("tcp", proxyAddr(proxy), auth, dial)
+		if err != nil {
+			return nil, err
+		}
+		return d.(xproxy.ContextDialer).DialContext(ctx, network, addr)
+	case "http", "https":
+		return dialConnect(ctx, dial, proxy, addr)
+	default:
+		return nil, fmt.Errorf("不支持的上游代理协议: %s", proxy.Scheme)
+	}
+}
+
+// dialConnect 通过 HTTP CONNECT 建立隧道
+func dialConnect(ctx context.Context, dial dialFunc, proxy *url.URL, addr string) (net.Conn, error) {
+	conn, err := dial(ctx, "tcp", proxyAddr(proxy))
+	if err != nil {
+		return nil, fmt.Errorf("连接上游代理失败: %w", err)
+	}
+	if proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("上游代理 TLS 握手失败: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		credential := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		request.Header.Set("Proxy-Authorization", "Basic "+credential)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := request.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("读取上游代理响应失败: %w", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("上游代理拒绝连接: %s", response.Status)
+	}
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// dialFunc 建立连接的函数，同时实现 SOCKS5 客户端需要的 Dialer 接口
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (f dialFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+func (f dialFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+// proxyAddr 获取代理服务器地址，未指定端口时使用协议默认端口
+func proxyAddr(proxy *url.URL) string {
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+	port := map[string]string{"http": "80", "https": "443", "socks5": "1080", "socks5h": "1080"}[proxy.Scheme]
+	return net.JoinHostPort(proxy.Hostname(), port)
+}
+
+// bufferedConn 读取代理响应时多读取的数据需要先返回给调用方
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
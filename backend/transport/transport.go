@@ -0,0 +1,186 @@
+package transport
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sbgayhub/chameleon/backend/config"
+	"github.com/sbgayhub/chameleon/backend/resolver"
+)
+
+// Options 渠道的出站设置，每个渠道使用独立的连接池
+type Options struct {
+	Channel  string // 渠道名称，为空表示直接转发的请求
+	Proxy    string // 渠道上游代理，为空时使用全局配置，direct 表示直连
+	CACert   string // 额外信任的 CA 证书文件（PEM），用于自签名证书的自建服务
+	Insecure bool   // 跳过上游证书校验
+}
+
+// settings 影响 Transport 构建的全局配置，变化后重建所有 Transport
+type settings struct {
+	connect, tls, header, idle time.Duration
+	idleConns                  int
+}
+
+var (
+	configMgr  *config.Manager
+	dns        *resolver.Resolver // 自定义解析器，为 nil 时使用系统解析
+	transports = make(map[Options]*http.Transport)
+	built      settings
+	mu         sync.Mutex
+)
+
+// Setup 设置出站配置来源，配置修改后对新请求立即生效
+func Setup(mgr *config.Manager) {
+	mu.Lock()
+	defer mu.Unlock()
+	configMgr = mgr
+}
+
+// SetResolver 设置出站连接使用的域名解析器，Host 模式下绕过被改写的 hosts 文件，传入 nil 恢复系统解析
+func SetResolver(r *resolver.Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	dns = r
+}
+
+// dialContext 建立 TCP 连接，设置了自定义解析器时先解析域名
+func dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mu.Lock()
+		r := dns
+		mu.Unlock()
+		if r == nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		return r.DialContext(ctx, dialer, network, addr)
+	}
+}
+
+// outbound 获取出站请求配置，旧配置文件中没有出站配置时使用默认值
+func outbound() *config.OutboundConfig {
+	mu.Lock()
+	mgr := configMgr
+	mu.Unlock()
+	if mgr == nil || mgr.GetConfig() == nil || mgr.GetConfig().Outbound == nil {
+		return &config.OutboundConfig{}
+	}
+	return mgr.GetConfig().Outbound
+}
+
+// current 获取当前的超时设置，未配置的项使用默认值
+func current() settings {
+	cfg := outbound()
+	seconds := func(v, def uint) time.Duration {
+		return time.Duration(cmp.Or(v, def)) * time.Second
+	}
+	return settings{
+		connect:   seconds(cfg.ConnectTimeout, 10),
+		tls:       seconds(cfg.TLSTimeout, 10),
+		header:    seconds(cfg.ResponseHeaderTimeout, 600),
+		idle:      seconds(cfg.StreamIdleTimeout, 300),
+		idleConns: cmp.Or(cfg.MaxIdleConnsPerHost, 16),
+	}
+}
+
+// Default 获取直接转发请求使用的 Transport
+func Default() *http.Transport {
+	t, _ := transport(Options{})
+	return t
+}
+
+// RoundTripper 获取指定出站设置的 RoundTripper，响应体长时间没有数据时会被中断
+func RoundTripper(opts Options) http.RoundTripper {
+	t, err := transport(opts)
+	if err != nil {
+		return errRoundTripper{err}
+	}
+	return &idleRoundTripper{next: t, idle: current().idle}
+}
+
+// Client 获取指定出站设置的 HTTP 客户端，不设置整体超时，避免中断长时间的流式响应
+func Client(opts Options) *http.Client {
+	return &http.Client{Transport: RoundTripper(opts)}
+}
+
+// transport 获取或创建指定出站设置的 Transport，超时配置变化时重建
+func transport(opts Options) (*http.Transport, error) {
+	s := current()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if s != built {
+		for _, t := range transports {
+			t.CloseIdleConnections()
+		}
+		clear(transports)
+		built = s
+	}
+	if t, ok := transports[opts]; ok {
+		return t, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.Insecure}
+	if opts.CACert != "" {
+		pool, err := loadCertPool(opts.CACert)
+		if err != nil {
+			slog.Error("加载渠道 CA 证书失败", "channel", opts.Channel, "path", opts.CACert, "error", err)
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	dialer := &net.Dialer{Timeout: s.connect, KeepAlive: 30 * time.Second}
+	t := &http.Transport{
+		Proxy: func(request *http.Request) (*url.URL, error) {
+			return proxyURL(opts.Proxy, request.URL)
+		},
+		DialContext:           dialContext(dialer),
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   s.tls,
+		ResponseHeaderTimeout: s.header,
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   s.idleConns,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	transports[opts] = t
+	return t, nil
+}
+
+// loadCertPool 在系统根证书的基础上追加 PEM 文件中的证书
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("文件中没有有效的 PEM 证书")
+	}
+	return pool, nil
+}
+
+// errRoundTripper 出站设置无效时，所有请求都返回创建时的错误
+type errRoundTripper struct {
+	err error
+}
+
+func (e errRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, e.err
+}
@@ -0,0 +1,49 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sbgayhub/chameleon/backend/config"
+)
+
+// 命令行加载数据目录中的配置后，出站请求使用 [outbound] 中的上游代理
+func TestClientUsesOutboundProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	dir := t.TempDir()
+	data := "schema_version = 1\n[outbound]\nproxy = \"" + proxy.URL + "\"\nno_proxy = [\"skip.test\"]\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := config.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Setup(mgr)
+	defer Setup(nil)
+
+	response, err := Client(Options{Channel: "test"}).Get("http://upstream.test/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if string(body) != "via proxy" || proxied != "http://upstream.test/v1/models" {
+		t.Errorf("request not sent through proxy: body=%q proxied=%q", body, proxied)
+	}
+
+	proxied = ""
+	if _, err := Client(Options{Channel: "test"}).Get("http://skip.test/"); err == nil || proxied != "" {
+		t.Errorf("no_proxy host was proxied: err=%v proxied=%q", err, proxied)
+	}
+}
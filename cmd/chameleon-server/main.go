@@ -0,0 +1,16 @@
+// chameleon-server 无界面版本，只包含命令行子命令，不依赖托盘和 Wails，可在服务器和容器中运行：
+//
+//	go build -o chameleon-server ./cmd/chameleon-server
+//	chameleon-server serve --data-dir /var/lib/chameleon
+package main
+
+import (
+	"os"
+
+	"github.com/sbgayhub/chameleon/backend/cli"
+)
+
+func main() {
+	cli.GUI = false
+	os.Exit(cli.Run(os.Args[1:]))
+}
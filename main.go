@@ -2,8 +2,10 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	"github.com/sbgayhub/chameleon/backend/application"
+	"github.com/sbgayhub/chameleon/backend/cli"
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
@@ -13,6 +15,11 @@ import (
 var assets embed.FS
 
 func main() {
+	// 命令行子命令（如 serve）不启动图形界面
+	if cli.IsCommand(os.Args[1:]) {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
 	app := application.NewApp()
 	err := wails.Run(&options.App{
 		Title:     "Chameleon",
@@ -23,15 +30,18 @@ func main() {
 		AssetServer: &assetserver.Options{
 			Assets: assets,
 		},
-		Frameless: true,
-		OnStartup: app.Startup,
+		Frameless:  true,
+		OnStartup:  app.Startup,
+		OnShutdown: app.Shutdown,
 		Bind: []interface{}{
 			app,
 			app.CertMgr,
 			app.ConfigMgr,
 			app.ChannelMgr,
 			app.StatsMgr,
+			app.RecordMgr,
 			app.UpdateMgr,
+			app.KeyMgr,
 		},
 		StartHidden: app.ConfigMgr.GetConfig().General.StartMinimized,
 		SingleInstanceLock: &options.SingleInstanceLock{